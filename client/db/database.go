@@ -12,6 +12,10 @@ type databaseClient struct {
 
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	kind        string
+	stats       func() map[string]int
+	removeStats func()
 }
 
 func (this *databaseClient) WithAddress(address string) {
@@ -52,6 +56,7 @@ func (this *databaseClient) Close() error {
 	if this.cancel != nil {
 		this.cancel()
 	}
+	this.uncollectPool()
 	return nil
 }
 
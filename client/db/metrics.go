@@ -0,0 +1,28 @@
+package db
+
+import "github.com/oylshe1314/framework/metrics"
+
+var poolConnections = metrics.NewGauge("db_pool_connections", "The connections of the db client pools by the state.", "client", "database", "state")
+
+// collectPool exposes the pool sizes returned by stats, by the state, until the client is closed.
+func (this *databaseClient) collectPool(kind string, stats func() map[string]int) {
+	this.kind = kind
+	this.stats = stats
+	this.removeStats = metrics.OnCollect(func() {
+		for state, n := range stats() {
+			poolConnections.Set(float64(n), kind, this.database, state)
+		}
+	})
+}
+
+func (this *databaseClient) uncollectPool() {
+	if this.removeStats == nil {
+		return
+	}
+
+	this.removeStats()
+	for state := range this.stats() {
+		poolConnections.Remove(this.kind, this.database, state)
+	}
+	this.stats, this.removeStats = nil, nil
+}
@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
 	"github.com/oylshe1314/framework/errors"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"sync/atomic"
 )
 
 type MongoClient struct {
@@ -12,6 +15,9 @@ type MongoClient struct {
 
 	client   *mongo.Client
 	database *mongo.Database
+
+	openConns  atomic.Int64
+	inUseConns atomic.Int64
 }
 
 func (this *MongoClient) Init() (err error) {
@@ -20,15 +26,32 @@ func (this *MongoClient) Init() (err error) {
 		return err
 	}
 
-	this.client, err = mongo.Connect(this.ctx, options.Client().ApplyURI(this.address))
+	this.client, err = mongo.Connect(this.ctx, options.Client().ApplyURI(this.address).SetPoolMonitor(&event.PoolMonitor{Event: this.poolEvent}))
 	if err != nil {
 		return err
 	}
 
+	this.collectPool("mongo", func() map[string]int {
+		return map[string]int{"open": int(this.openConns.Load()), "in_use": int(this.inUseConns.Load())}
+	})
+
 	this.database = this.client.Database(this.Database())
 	return nil
 }
 
+func (this *MongoClient) poolEvent(e *event.PoolEvent) {
+	switch e.Type {
+	case event.ConnectionCreated:
+		this.openConns.Add(1)
+	case event.ConnectionClosed:
+		this.openConns.Add(-1)
+	case event.GetSucceeded:
+		this.inUseConns.Add(1)
+	case event.ConnectionReturned:
+		this.inUseConns.Add(-1)
+	}
+}
+
 func (this *MongoClient) Close() (err error) {
 	_ = this.databaseClient.Close()
 	if this.client != nil {
@@ -42,6 +65,11 @@ func (this *MongoClient) Collection(name string) *mongo.Collection {
 }
 
 func (this *MongoClient) Counter(key string, inc uint64) (uint64, error) {
+	return this.CounterContext(this.Context(), key, inc)
+}
+
+// CounterContext is the same as Counter, but with the context of the caller, e.g. the context of a message.
+func (this *MongoClient) CounterContext(ctx context.Context, key string, inc uint64) (uint64, error) {
 	if inc < 1 {
 		inc = 1
 	}
@@ -50,7 +78,7 @@ func (this *MongoClient) Counter(key string, inc uint64) (uint64, error) {
 	var counter = &Counter[string, uint64]{Id: key}
 	for {
 		var err = this.Collection("counter").FindOneAndUpdate(
-			this.Context(),
+			ctx,
 			bson.M{"_id": counter.Id},
 			bson.M{"$inc": bson.M{"value": inc}},
 			&options.FindOneAndUpdateOptions{Upsert: &upsert},
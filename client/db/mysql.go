@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/oylshe1314/framework/errors"
@@ -50,6 +51,11 @@ func (this *MysqlClient) Init() (err error) {
 	if this.connMaxLifetime > 0 {
 		this.DB.SetConnMaxLifetime(time.Duration(this.connMaxLifetime))
 	}
+
+	this.collectPool("mysql", func() map[string]int {
+		var stats = this.DB.Stats()
+		return map[string]int{"open": stats.OpenConnections, "in_use": stats.InUse, "idle": stats.Idle, "max_open": stats.MaxOpenConnections}
+	})
 	return nil
 }
 
@@ -70,22 +76,28 @@ func (this *MysqlClient) Close() (err error) {
 //		`value` bigint unsigned not null
 //	);
 func (this *MysqlClient) Counter(key string, inc uint64) (uint64, error) {
+	return this.CounterContext(this.Context(), key, inc)
+}
+
+// CounterContext is the same as Counter, but with the context of the caller, e.g. the context of a message.
+func (this *MysqlClient) CounterContext(ctx context.Context, key string, inc uint64) (uint64, error) {
 	if inc == 0 {
 		return 0, nil
 	}
 
 	var counter = &Counter[string, uint64]{Id: key}
 
-	tx, err := this.Begin()
+	tx, err := this.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
 
-	row := tx.QueryRowContext(this.Context(), "select `value` from counter where `key`=?;", counter.Id)
+	row := tx.QueryRowContext(ctx, "select `value` from counter where `key`=?;", counter.Id)
 	if err = row.Scan(&counter.Value); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			counter.Value = 0
-			_, err = tx.Exec("insert into counter (`key`, `value`) value (?, ?);", counter.Id, counter.Value+inc)
+			_, err = tx.ExecContext(ctx, "insert into counter (`key`, `value`) value (?, ?);", counter.Id, counter.Value+inc)
 			if err != nil {
 				return 0, err
 			}
@@ -93,7 +105,7 @@ func (this *MysqlClient) Counter(key string, inc uint64) (uint64, error) {
 			return 0, err
 		}
 	} else {
-		_, err = tx.Exec("update counter set `value`=? where `key`=?;", counter.Value+inc, counter.Id)
+		_, err = tx.ExecContext(ctx, "update counter set `value`=? where `key`=?;", counter.Value+inc, counter.Id)
 		if err != nil {
 			return 0, err
 		}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"github.com/oylshe1314/framework/client/db/redis"
 	"github.com/oylshe1314/framework/util"
 )
@@ -18,7 +19,7 @@ func (this *RedisClient) WithUsername(username string) {
 }
 
 func (this *RedisClient) WithPassword(password string) {
-	this.username = password
+	this.password = password
 }
 
 func (this *RedisClient) Init() (err error) {
@@ -37,6 +38,10 @@ func (this *RedisClient) Init() (err error) {
 
 	this.Redis = redis.OpenRedis(this.address, this.username, this.password, db)
 
+	this.collectPool("redis", func() map[string]int {
+		var stats = this.Redis.PoolStats()
+		return map[string]int{"total": int(stats.TotalConns), "idle": int(stats.IdleConns), "stale": int(stats.StaleConns)}
+	})
 	return
 }
 
@@ -50,11 +55,16 @@ func (this *RedisClient) Close() (err error) {
 
 // Counter return the value before the increment
 func (this *RedisClient) Counter(key string, inc uint64) (counter uint64, err error) {
+	return this.CounterContext(this.Context(), key, inc)
+}
+
+// CounterContext is the same as Counter, but with the context of the caller, e.g. the context of a message.
+func (this *RedisClient) CounterContext(ctx context.Context, key string, inc uint64) (counter uint64, err error) {
 	if inc == 0 {
 		return 0, nil
 	}
 
-	val, err := this.String(this.Context(), "incrby", key, util.IntegerToString(inc))
+	val, err := this.String(ctx, "incrby", key, util.IntegerToString(inc))
 	if err != nil {
 		return 0, err
 	}
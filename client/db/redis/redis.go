@@ -140,6 +140,7 @@ func (sm StringMap) Decode(v interface{}) error {
 
 type Redis interface {
 	Close() error
+	PoolStats() *PoolStats
 	Exec(ctx context.Context, cmd string, args ...interface{}) error
 	String(ctx context.Context, cmd string, args ...interface{}) (string, error)
 	Strings(ctx context.Context, cmd string, args ...interface{}) (Strings, error)
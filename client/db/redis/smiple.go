@@ -6,6 +6,8 @@ import (
 	"strings"
 )
 
+type PoolStats = std.PoolStats
+
 type simple struct {
 	client std.UniversalClient
 }
@@ -23,6 +25,10 @@ func (this *simple) Close() error {
 	return this.client.Close()
 }
 
+func (this *simple) PoolStats() *PoolStats {
+	return this.client.PoolStats()
+}
+
 func (this *simple) Exec(ctx context.Context, cmd string, args ...interface{}) error {
 	args = append([]interface{}{cmd}, args...)
 	var dr = this.client.Do(ctx, args...)
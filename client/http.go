@@ -2,10 +2,12 @@ package client
 
 import (
 	"bytes"
+	"context"
 	json "github.com/json-iterator/go"
 	"github.com/oylshe1314/framework/errors"
 	"github.com/oylshe1314/framework/log"
 	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/trace"
 	"github.com/oylshe1314/framework/util"
 	"net/http"
 	"net/url"
@@ -30,6 +32,16 @@ func (hh HttpHeader) Values(key string) []string {
 	return http.Header(hh).Values(key)
 }
 
+// TraceHeader returns the header with the traceparent of ctx, it propagates the trace
+// through the requests without a context, e.g. the requests of the rpc clients.
+func TraceHeader(ctx context.Context) HttpHeader {
+	var header = HttpHeader{}
+	if traceparent := trace.Inject(ctx); len(traceparent) > 0 {
+		header.Set(trace.HeaderTraceparent, traceparent)
+	}
+	return header
+}
+
 type HttpClient struct {
 	ssl     bool
 	network string
@@ -93,34 +105,30 @@ func (this *HttpClient) Close() error {
 }
 
 func (this *HttpClient) Get(path string, query url.Values, data interface{}, headers ...HttpHeader) (*message.Reply, error) {
+	return this.GetContext(context.Background(), path, query, data, headers...)
+}
+
+// GetContext sends the GET request with the trace context of ctx in the traceparent header.
+func (this *HttpClient) GetContext(ctx context.Context, path string, query url.Values, data interface{}, headers ...HttpHeader) (*message.Reply, error) {
 	reqUrl := this.httpUrl.JoinPath(path)
 	reqUrl.RawQuery = query.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, reqUrl.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Add("Accept", "application/json")
 
-	for _, header := range headers {
-		for key, values := range header {
-			for _, value := range values {
-				req.Header.Add(key, value)
-			}
-		}
-	}
-
-	var reply = &message.Reply{Data: data}
-	err = util.JsonResponseDecoder(reply).Decode(this.httpClient.Do(req))
-	if err != nil {
-		return nil, err
-	}
-
-	return reply, nil
+	return this.do(req, data, headers...)
 }
 
 func (this *HttpClient) Post(path string, query url.Values, msg, data interface{}, headers ...HttpHeader) (*message.Reply, error) {
+	return this.PostContext(context.Background(), path, query, msg, data, headers...)
+}
+
+// PostContext sends the POST request with the trace context of ctx in the traceparent header.
+func (this *HttpClient) PostContext(ctx context.Context, path string, query url.Values, msg, data interface{}, headers ...HttpHeader) (*message.Reply, error) {
 	reqUrl := this.httpUrl.JoinPath(path)
 	reqUrl.RawQuery = query.Encode()
 
@@ -130,7 +138,7 @@ func (this *HttpClient) Post(path string, query url.Values, msg, data interface{
 		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, reqUrl.String(), &buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqUrl.String(), &buf)
 	if err != nil {
 		return nil, err
 	}
@@ -138,6 +146,11 @@ func (this *HttpClient) Post(path string, query url.Values, msg, data interface{
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 
+	return this.do(req, data, headers...)
+}
+
+// do sends the request in a client span, the traceparent header given by the caller is used as the parent.
+func (this *HttpClient) do(req *http.Request, data interface{}, headers ...HttpHeader) (reply *message.Reply, err error) {
 	for _, header := range headers {
 		for key, values := range header {
 			for _, value := range values {
@@ -146,7 +159,21 @@ func (this *HttpClient) Post(path string, query url.Values, msg, data interface{
 		}
 	}
 
-	var reply = &message.Reply{Data: data}
+	var ctx = req.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = trace.Extract(ctx, req.Header.Get(trace.HeaderTraceparent))
+	}
+
+	ctx, span := trace.Start(ctx, "HTTP "+req.Method+" "+req.URL.Path, trace.KindClient)
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+
+	req = req.WithContext(ctx)
+	req.Header.Set(trace.HeaderTraceparent, trace.Inject(ctx))
+
+	reply = &message.Reply{Data: data}
 	err = util.JsonResponseDecoder(reply).Decode(this.httpClient.Do(req))
 	if err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"github.com/gorilla/websocket"
 	"github.com/oylshe1314/framework/errors"
 	. "github.com/oylshe1314/framework/http/ws"
@@ -31,6 +32,13 @@ func (this *WebSocketClient) Dial(pattern string) error {
 		return err
 	}
 
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+
 	u.Path = pattern
 
 	wc, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
@@ -49,6 +57,14 @@ func (this *WebSocketClient) Send(modId, msgId uint16, v interface{}) error {
 	return this.conn.Send(modId, msgId, v)
 }
 
+// SendContext sends v with the trace context of ctx in the frame metadata.
+func (this *WebSocketClient) SendContext(ctx context.Context, modId, msgId uint16, v interface{}) error {
+	if this.conn == nil {
+		return errors.Error("please connect server first")
+	}
+	return this.conn.SendContext(ctx, modId, msgId, v)
+}
+
 func (this *WebSocketClient) Read() (*Message, error) {
 	if this.conn == nil {
 		return nil, errors.Error("please connect server first")
@@ -1,12 +1,22 @@
 package client
 
 import (
+	"context"
 	"github.com/oylshe1314/framework/errors"
 	"github.com/oylshe1314/framework/log"
 	. "github.com/oylshe1314/framework/net"
 	"net"
+	"sync"
+	"time"
 )
 
+type bufferedMessage struct {
+	ctx   context.Context
+	modId uint16
+	msgId uint16
+	v     interface{}
+}
+
 type NetClient struct {
 	ConnMux
 
@@ -15,7 +25,19 @@ type NetClient struct {
 
 	logger log.Logger
 
-	conn *Conn
+	locker sync.RWMutex
+	conn   *Conn
+
+	closed    bool
+	closeChan chan struct{}
+
+	policy       *ReconnectPolicy
+	reconnecting bool
+	buffer       []*bufferedMessage
+	failed       error // the error of giving up reconnecting, the client is unusable until dialed again
+
+	droppedHandler     func(err error)
+	reconnectedHandler func(conn *Conn)
 }
 
 func (this *NetClient) WithNetwork(network string) {
@@ -38,6 +60,21 @@ func (this *NetClient) SetLogger(logger log.Logger) {
 	this.logger = logger
 }
 
+// SetReconnectPolicy enables reconnecting when the connection served by Work dropped, nil disables it.
+func (this *NetClient) SetReconnectPolicy(policy *ReconnectPolicy) {
+	this.policy = policy
+}
+
+// DroppedHandler sets the hook called when the connection dropped and the client is going to reconnect.
+func (this *NetClient) DroppedHandler(handler func(err error)) {
+	this.droppedHandler = handler
+}
+
+// ReconnectedHandler sets the hook called when the client reconnected to the server.
+func (this *NetClient) ReconnectedHandler(handler func(conn *Conn)) {
+	this.reconnectedHandler = handler
+}
+
 func (this *NetClient) Init() (err error) {
 	if this.logger == nil {
 		this.logger = log.DefaultLogger
@@ -68,19 +105,143 @@ func (this *NetClient) Init() (err error) {
 
 	this.network = addr.Network()
 	this.address = addr.String()
+	this.closeChan = make(chan struct{})
 
 	return nil
 }
 
 func (this *NetClient) Close() (err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if !this.closed {
+		this.closed = true
+		if this.closeChan != nil {
+			close(this.closeChan)
+		}
+	}
+
 	if this.conn != nil {
 		return this.conn.Close()
 	}
 	return
 }
 
+func (this *NetClient) getConn() *Conn {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return this.conn
+}
+
+func (this *NetClient) isClosed() bool {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return this.closed
+}
+
 func (this *NetClient) Work() error {
-	return this.conn.Serve()
+	for {
+		var conn = this.getConn()
+		if conn == nil {
+			return errors.Error("please connect server first")
+		}
+
+		var err = conn.Serve()
+		if this.isClosed() || this.policy == nil {
+			return err
+		}
+
+		if err == nil {
+			err = ErrConnClosed
+		}
+
+		this.logger.Warnf("The connection was dropped, address: %s, error: %v", this.address, err)
+
+		this.locker.Lock()
+		this.reconnecting = true
+		this.locker.Unlock()
+
+		if this.droppedHandler != nil {
+			this.droppedHandler(err)
+		}
+
+		err = this.reconnect()
+		if err != nil {
+			this.locker.Lock()
+			this.reconnecting = false
+			this.buffer = nil
+			if !this.closed {
+				this.failed = err
+			}
+			this.locker.Unlock()
+			return err
+		}
+	}
+}
+
+func (this *NetClient) reconnect() error {
+	var begin = time.Now()
+	for attempt := 1; ; attempt++ {
+		if this.policy.exhausted(attempt, begin) {
+			return errors.Errorf("reconnect to '%s' gave up after %d attempts", this.address, attempt-1)
+		}
+
+		select {
+		case <-this.closeChan:
+			return ErrConnClosed
+		case <-time.After(this.policy.interval(attempt)):
+		}
+
+		cc, err := net.Dial(this.network, this.address)
+		if err != nil {
+			this.logger.Warnf("Reconnect failed, address: %s, attempt: %d, error: %v", this.address, attempt, err)
+			continue
+		}
+
+		var conn = NewConn(cc, this.logger, &this.ConnMux)
+
+		this.locker.Lock()
+		if this.closed {
+			this.locker.Unlock()
+			_ = cc.Close()
+			return ErrConnClosed
+		}
+
+		this.conn = conn
+		this.locker.Unlock()
+
+		this.replay(conn)
+
+		this.logger.Infof("Reconnect succeed, address: %s, attempt: %d", this.address, attempt)
+
+		if this.reconnectedHandler != nil {
+			this.reconnectedHandler(conn)
+		}
+		return nil
+	}
+}
+
+// replay sends the buffered messages in order out of the lock, the messages sent meanwhile are still buffered
+// until the buffer is empty.
+func (this *NetClient) replay(conn *Conn) {
+	for {
+		this.locker.Lock()
+		var buffer = this.buffer
+		this.buffer = nil
+		if len(buffer) == 0 {
+			this.reconnecting = false
+			this.locker.Unlock()
+			return
+		}
+		this.locker.Unlock()
+
+		for _, bm := range buffer {
+			var err = conn.SendContext(bm.ctx, bm.modId, bm.msgId, bm.v)
+			if err != nil {
+				this.logger.Errorf("Send buffered message failed, ModId: %d, MsgId: %d, error: %v", bm.modId, bm.msgId, err)
+			}
+		}
+	}
 }
 
 func (this *NetClient) Dial() error {
@@ -88,20 +249,68 @@ func (this *NetClient) Dial() error {
 	if err != nil {
 		return err
 	}
+
+	this.locker.Lock()
 	this.conn = NewConn(conn, this.logger, &this.ConnMux)
+	this.failed = nil
+	this.locker.Unlock()
 	return nil
 }
 
 func (this *NetClient) Send(modId, msgId uint16, v interface{}) error {
-	if this.conn == nil {
+	return this.SendContext(context.Background(), modId, msgId, v)
+}
+
+// SendContext sends v with the trace context of ctx in the frame metadata.
+func (this *NetClient) SendContext(ctx context.Context, modId, msgId uint16, v interface{}) error {
+	this.locker.Lock()
+	var conn = this.conn
+	if conn == nil {
+		this.locker.Unlock()
 		return errors.Error("please connect server first")
 	}
-	return this.conn.Send(modId, msgId, v)
+
+	if this.failed != nil {
+		defer this.locker.Unlock()
+		return this.failed
+	}
+
+	if this.reconnecting {
+		defer this.locker.Unlock()
+		if len(this.buffer) < this.policy.BufferSize {
+			this.buffer = append(this.buffer, &bufferedMessage{ctx: ctx, modId: modId, msgId: msgId, v: v})
+			return nil
+		}
+		return ErrReconnecting
+	}
+	this.locker.Unlock()
+
+	return conn.SendContext(ctx, modId, msgId, v)
+}
+
+func (this *NetClient) Call(ctx context.Context, modId, msgId uint16, req, res interface{}) error {
+	this.locker.RLock()
+	var conn, reconnecting, failed = this.conn, this.reconnecting, this.failed
+	this.locker.RUnlock()
+
+	if conn == nil {
+		return errors.Error("please connect server first")
+	}
+
+	if failed != nil {
+		return failed
+	}
+
+	if reconnecting {
+		return ErrReconnecting
+	}
+	return conn.Call(ctx, modId, msgId, req, res)
 }
 
 func (this *NetClient) Read() (*Message, error) {
-	if this.conn == nil {
+	var conn = this.getConn()
+	if conn == nil {
 		return nil, errors.Error("please connect server first")
 	}
-	return this.conn.Read()
+	return conn.Read()
 }
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"github.com/oylshe1314/framework/log"
+	. "github.com/oylshe1314/framework/net"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReconnectInterval(t *testing.T) {
+	var policy = &ReconnectPolicy{InitialInterval: time.Millisecond * 100, MaxInterval: time.Second, Multiplier: 2}
+	var expected = []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, ms := range expected {
+		if interval := policy.interval(i + 1); interval != ms*time.Millisecond {
+			t.Fatalf("attempt %d: interval %v, expected %v", i+1, interval, ms*time.Millisecond)
+		}
+	}
+
+	policy.Jitter = 0.2
+	for range 100 {
+		if interval := policy.interval(1); interval < time.Millisecond*80 || interval > time.Millisecond*120 {
+			t.Fatalf("interval %v is out of the jitter", interval)
+		}
+	}
+
+	policy.MaxAttempts = 3
+	if policy.exhausted(3, time.Now()) || !policy.exhausted(4, time.Now()) {
+		t.Fatal("the attempts should be exhausted after MaxAttempts")
+	}
+}
+
+func TestNetClientReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var received = make(chan string, 4)
+	var serverMux = &ConnMux{}
+	serverMux.MessageHandler(1, 1, func(msg *Message) {
+		var s string
+		_ = msg.Read(&s)
+		received <- s
+	})
+
+	var accepted = make(chan *Conn, 2)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			var conn = NewConn(c, log.DefaultLogger, serverMux)
+			go conn.Serve()
+			accepted <- conn
+		}
+	}()
+
+	var nc = &NetClient{}
+	nc.WithNetwork("tcp")
+	nc.WithAddress(l.Addr().String())
+	nc.SetReconnectPolicy(&ReconnectPolicy{InitialInterval: time.Millisecond * 10, Multiplier: 2, BufferSize: 1})
+	if err = nc.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	var sendErrs = make(chan error, 2)
+	nc.DroppedHandler(func(err error) {
+		sendErrs <- nc.Send(1, 1, "buffered")
+		sendErrs <- nc.Send(1, 1, "rejected")
+	})
+	var reconnected = make(chan struct{}, 1)
+	nc.ReconnectedHandler(func(conn *Conn) {
+		reconnected <- struct{}{}
+	})
+
+	if err = nc.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	go nc.Work()
+
+	_ = (<-accepted).Close()
+	if err = <-sendErrs; err != nil {
+		t.Fatalf("the message should be buffered, %v", err)
+	}
+	if err = <-sendErrs; err != ErrReconnecting {
+		t.Fatalf("expected ErrReconnecting when the buffer is full, got %v", err)
+	}
+
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second * 3):
+		t.Fatal("reconnect timeout")
+	}
+	<-accepted
+
+	select {
+	case s := <-received:
+		if s != "buffered" {
+			t.Fatalf("unexpected message %q", s)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("the buffered message was not flushed")
+	}
+}
+
+func TestNetClientGiveUp(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nc = &NetClient{}
+	nc.WithNetwork("tcp")
+	nc.WithAddress(l.Addr().String())
+	nc.SetReconnectPolicy(&ReconnectPolicy{InitialInterval: time.Millisecond * 10, MaxAttempts: 2, BufferSize: 1})
+	if err = nc.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	if err = nc.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+	_ = c.Close()
+
+	var workErr = nc.Work()
+	if workErr == nil {
+		t.Fatal("Work should return the error of giving up")
+	}
+
+	if err = nc.Send(1, 1, "lost"); err != workErr {
+		t.Fatalf("the send after giving up should fail, got %v", err)
+	}
+}
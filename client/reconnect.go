@@ -0,0 +1,71 @@
+package client
+
+import (
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/util"
+	"time"
+)
+
+var ErrReconnecting = errors.Error("the connection is reconnecting")
+
+const (
+	defaultReconnectInitialInterval = time.Millisecond * 500
+	defaultReconnectMaxInterval     = time.Second * 30
+	defaultReconnectMultiplier      = 2.0
+	defaultReconnectJitter          = 0.2
+)
+
+// ReconnectPolicy describes how a client reconnects after its connection dropped.
+// The interval grows exponentially from InitialInterval to MaxInterval, and is randomized by Jitter(0~1).
+// Reconnecting gives up after MaxAttempts attempts or MaxDuration, zero means no limit, the client reports the
+// error of giving up on sending until it is dialed again.
+// While reconnecting, at most BufferSize messages are buffered and sent after reconnected,
+// the others are rejected with ErrReconnecting, zero means rejecting all.
+type ReconnectPolicy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+	MaxAttempts     int
+	MaxDuration     time.Duration
+	BufferSize      int
+}
+
+func NewReconnectPolicy() *ReconnectPolicy {
+	return &ReconnectPolicy{
+		InitialInterval: defaultReconnectInitialInterval,
+		MaxInterval:     defaultReconnectMaxInterval,
+		Multiplier:      defaultReconnectMultiplier,
+		Jitter:          defaultReconnectJitter,
+	}
+}
+
+func (this *ReconnectPolicy) interval(attempt int) time.Duration {
+	var initial = util.If(this.InitialInterval > 0, this.InitialInterval, defaultReconnectInitialInterval)
+	var max = util.If(this.MaxInterval > 0, this.MaxInterval, defaultReconnectMaxInterval)
+	var multiplier = util.If(this.Multiplier >= 1, this.Multiplier, defaultReconnectMultiplier)
+
+	var interval = float64(initial)
+	for i := 1; i < attempt && interval < float64(max); i++ {
+		interval *= multiplier
+	}
+	if interval > float64(max) {
+		interval = float64(max)
+	}
+
+	if this.Jitter > 0 {
+		var jitter = util.If(this.Jitter > 1, 1.0, this.Jitter)
+		interval += interval * jitter * (util.NewRand().Float64()*2 - 1)
+	}
+	return time.Duration(interval)
+}
+
+func (this *ReconnectPolicy) exhausted(attempt int, begin time.Time) bool {
+	if this.MaxAttempts > 0 && attempt > this.MaxAttempts {
+		return true
+	}
+	if this.MaxDuration > 0 && time.Since(begin) >= this.MaxDuration {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package rpc
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/util"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Balancer selects a node from the available nodes of a service, the nodes are sorted by AppId.
+// The key is supplied by the caller of the Key* methods, it's empty for the other methods.
+type Balancer interface {
+	Select(nodes []*sd.ServerNode, key string) *sd.ServerNode
+}
+
+// InFlightTracker is implemented by the balancers which need to know the calls in flight,
+// Acquire is called before a call is sent to the node and Release after it returned.
+type InFlightTracker interface {
+	Acquire(node *sd.ServerNode)
+	Release(node *sd.ServerNode)
+}
+
+func nodeKey(node *sd.ServerNode) string {
+	return node.Name + ":" + strconv.FormatUint(uint64(node.AppId), 10)
+}
+
+func nodeWeight(node *sd.ServerNode) float64 {
+	if node.Inner == nil || node.Inner.Extra == nil {
+		return 1
+	}
+
+	switch w := node.Inner.Extra["weight"].(type) {
+	case nil:
+		return 1
+	case float64:
+		return w
+	case float32:
+		return float64(w)
+	case int:
+		return float64(w)
+	case int64:
+		return float64(w)
+	case uint32:
+		return float64(w)
+	case string:
+		var f float64
+		if util.StringToFloat2(w, &f) == nil {
+			return f
+		}
+	}
+	return 1
+}
+
+type randomBalancer struct{}
+
+// NewRandomBalancer returns a balancer selects a node at random, it's the default balancer.
+func NewRandomBalancer() Balancer {
+	return &randomBalancer{}
+}
+
+func (this *randomBalancer) Select(nodes []*sd.ServerNode, key string) *sd.ServerNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[util.NewRand().IntN(len(nodes))]
+}
+
+type roundRobinBalancer struct {
+	next atomic.Uint64
+}
+
+func NewRoundRobinBalancer() Balancer {
+	return &roundRobinBalancer{}
+}
+
+func (this *roundRobinBalancer) Select(nodes []*sd.ServerNode, key string) *sd.ServerNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[(this.next.Add(1)-1)%uint64(len(nodes))]
+}
+
+type weightedBalancer struct{}
+
+// NewWeightedBalancer returns a balancer selects a node at random by the 'weight' of the inner network extra,
+// the weight of a node is 1 if it's not set.
+func NewWeightedBalancer() Balancer {
+	return &weightedBalancer{}
+}
+
+func (this *weightedBalancer) Select(nodes []*sd.ServerNode, key string) *sd.ServerNode {
+	var weighted = make([]*sd.ServerNode, 0, len(nodes))
+	var weights = make([]float64, 0, len(nodes))
+	for _, node := range nodes {
+		var weight = nodeWeight(node)
+		if weight > 0 {
+			weighted = append(weighted, node)
+			weights = append(weights, weight)
+		}
+	}
+
+	if len(weighted) == 0 {
+		return nil
+	}
+
+	return util.RandomWeights(weighted, func(i int) float64 { return weights[i] })
+}
+
+type leastInFlightBalancer struct {
+	locker   sync.Mutex
+	inFlight map[string]int64
+}
+
+// NewLeastInFlightBalancer returns a balancer selects the node with the fewest calls in flight.
+func NewLeastInFlightBalancer() Balancer {
+	return &leastInFlightBalancer{inFlight: map[string]int64{}}
+}
+
+func (this *leastInFlightBalancer) Select(nodes []*sd.ServerNode, key string) *sd.ServerNode {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var selected *sd.ServerNode
+	var least int64
+	for _, node := range nodes {
+		var n = this.inFlight[nodeKey(node)]
+		if selected == nil || n < least {
+			selected = node
+			least = n
+		}
+	}
+	return selected
+}
+
+func (this *leastInFlightBalancer) Acquire(node *sd.ServerNode) {
+	this.locker.Lock()
+	this.inFlight[nodeKey(node)] += 1
+	this.locker.Unlock()
+}
+
+func (this *leastInFlightBalancer) Release(node *sd.ServerNode) {
+	this.locker.Lock()
+	var key = nodeKey(node)
+	if this.inFlight[key] <= 1 {
+		delete(this.inFlight, key)
+	} else {
+		this.inFlight[key] -= 1
+	}
+	this.locker.Unlock()
+}
+
+const defaultHashReplicas = 160
+
+type hashRing struct {
+	nodes  []*sd.ServerNode
+	hashes []uint32
+	owners map[uint32]*sd.ServerNode
+}
+
+func (this *hashRing) match(nodes []*sd.ServerNode) bool {
+	if len(this.nodes) != len(nodes) {
+		return false
+	}
+	for i, node := range nodes {
+		if this.nodes[i].AppId != node.AppId || this.nodes[i].Name != node.Name {
+			return false
+		}
+	}
+	return true
+}
+
+type consistentHashBalancer struct {
+	replicas int
+
+	locker sync.Mutex
+	rings  map[string]*hashRing
+}
+
+// NewConsistentHashBalancer returns a balancer selects the node by the consistent hash of the key,
+// replicas is the number of the virtual nodes of each node. A node is selected at random if the key is empty.
+func NewConsistentHashBalancer(replicas int) Balancer {
+	if replicas <= 0 {
+		replicas = defaultHashReplicas
+	}
+	return &consistentHashBalancer{replicas: replicas, rings: map[string]*hashRing{}}
+}
+
+func hashKey(key string) uint32 {
+	var h = fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// getRing returns the ring of the service of the nodes, it's rebuilt only if the nodes were changed.
+func (this *consistentHashBalancer) getRing(nodes []*sd.ServerNode) *hashRing {
+	var service = nodes[0].Name
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var ring = this.rings[service]
+	if ring != nil && ring.match(nodes) {
+		return ring
+	}
+
+	ring = &hashRing{nodes: nodes, owners: map[uint32]*sd.ServerNode{}}
+	for _, node := range nodes {
+		var key = nodeKey(node)
+		for r := 0; r < this.replicas; r++ {
+			var hash = hashKey(key + "#" + strconv.Itoa(r))
+			if _, ok := ring.owners[hash]; ok {
+				continue
+			}
+			ring.owners[hash] = node
+			ring.hashes = append(ring.hashes, hash)
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+
+	this.rings[service] = ring
+	return ring
+}
+
+func (this *consistentHashBalancer) Select(nodes []*sd.ServerNode, key string) *sd.ServerNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	if len(key) == 0 {
+		return nodes[util.NewRand().IntN(len(nodes))]
+	}
+
+	var ring = this.getRing(nodes)
+	var hash = hashKey(key)
+	var i = sort.Search(len(ring.hashes), func(i int) bool { return ring.hashes[i] >= hash })
+	if i == len(ring.hashes) {
+		i = 0
+	}
+	return ring.owners[ring.hashes[i]]
+}
+
+type balancers struct {
+	balancer  Balancer
+	balancers map[string]Balancer
+}
+
+// SetBalancer sets the default balancer of all the services.
+func (this *balancers) SetBalancer(balancer Balancer) {
+	this.balancer = balancer
+}
+
+// SetServiceBalancer sets the balancer of the service, it overrides the default balancer.
+func (this *balancers) SetServiceBalancer(service string, balancer Balancer) {
+	if this.balancers == nil {
+		this.balancers = map[string]Balancer{}
+	}
+	this.balancers[service] = balancer
+}
+
+func (this *balancers) getBalancer(service string) Balancer {
+	var balancer = this.balancers[service]
+	if balancer != nil {
+		return balancer
+	}
+	if this.balancer != nil {
+		return this.balancer
+	}
+	return defaultBalancer
+}
+
+var defaultBalancer = NewRandomBalancer()
+
+func selectNode[N any](balancer Balancer, nodes map[uint32]N, key string, serverNode func(N) *sd.ServerNode) (n N) {
+	if len(nodes) == 0 {
+		return
+	}
+
+	var list = make([]*sd.ServerNode, 0, len(nodes))
+	for _, node := range nodes {
+		list = append(list, serverNode(node))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].AppId < list[j].AppId })
+
+	var selected = balancer.Select(list, key)
+	if selected == nil {
+		return
+	}
+	return nodes[selected.AppId]
+}
+
+func acquireNode(balancer Balancer, node *sd.ServerNode) func() {
+	tracker, ok := balancer.(InFlightTracker)
+	if !ok {
+		return func() {}
+	}
+
+	tracker.Acquire(node)
+	return func() {
+		tracker.Release(node)
+	}
+}
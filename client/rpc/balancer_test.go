@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"testing"
+)
+
+func testNodes(n int) []*sd.ServerNode {
+	var nodes = make([]*sd.ServerNode, n)
+	for i := range nodes {
+		nodes[i] = &sd.ServerNode{Name: "test", AppId: uint32(i + 1), Inner: &sd.ServerNetwork{Extra: map[string]any{"weight": float64(i)}}}
+	}
+	return nodes
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	var nodes = testNodes(3)
+	var balancer = NewRoundRobinBalancer()
+	for i := 0; i < 6; i++ {
+		var node = balancer.Select(nodes, "")
+		if node != nodes[i%3] {
+			t.Fatalf("round %d selected appId %d", i, node.AppId)
+		}
+	}
+}
+
+func TestWeightedBalancer(t *testing.T) {
+	var nodes = testNodes(3)
+	var balancer = NewWeightedBalancer()
+	for i := 0; i < 100; i++ {
+		if balancer.Select(nodes, "").AppId == 1 {
+			t.Fatal("the node with zero weight was selected")
+		}
+	}
+}
+
+func TestLeastInFlightBalancer(t *testing.T) {
+	var nodes = testNodes(2)
+	var balancer = NewLeastInFlightBalancer()
+	var tracker = balancer.(InFlightTracker)
+
+	tracker.Acquire(nodes[0])
+	if balancer.Select(nodes, "") != nodes[1] {
+		t.Fatal("the busy node was selected")
+	}
+	tracker.Release(nodes[0])
+	tracker.Acquire(nodes[1])
+	if balancer.Select(nodes, "") != nodes[0] {
+		t.Fatal("the busy node was selected")
+	}
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	var nodes = testNodes(5)
+	var balancer = NewConsistentHashBalancer(0)
+
+	var selected = map[string]uint32{}
+	for _, key := range []string{"10001", "10002", "10003", "10004", "10005"} {
+		selected[key] = balancer.Select(nodes, key).AppId
+	}
+
+	for key, appId := range selected {
+		if balancer.Select(nodes, key).AppId != appId {
+			t.Fatalf("key %s moved with the same nodes", key)
+		}
+	}
+
+	var removed = nodes[4]
+	for key, appId := range selected {
+		if appId != removed.AppId && balancer.Select(nodes[:4], key).AppId != appId {
+			t.Fatalf("key %s moved while its node was not removed", key)
+		}
+	}
+}
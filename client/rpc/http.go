@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"github.com/oylshe1314/framework/client"
 	"github.com/oylshe1314/framework/client/sd"
 	"github.com/oylshe1314/framework/errors"
@@ -17,6 +18,8 @@ type HttpRpcNode struct {
 }
 
 type HttpRpcClient struct {
+	balancers
+
 	logger log.Logger
 	locker sync.RWMutex
 	nodes  map[string]map[uint32]*HttpRpcNode
@@ -125,16 +128,16 @@ func (this *HttpRpcClient) Node(service string, appId uint32) *HttpRpcNode {
 	return nodes[appId]
 }
 
+// RandNode returns the node selected by the balancer of the service without a key, it's random by default.
 func (this *HttpRpcClient) RandNode(service string) *HttpRpcNode {
-	var nodes = util.MapValues(this.Nodes(service))
-	if len(nodes) == 0 {
-		return nil
-	}
+	return this.KeyNode(service, "")
+}
 
-	return nodes[util.NewRand().IntN(len(nodes))]
+func (this *HttpRpcClient) KeyNode(service, key string) *HttpRpcNode {
+	return selectNode(this.getBalancer(service), this.Nodes(service), key, func(node *HttpRpcNode) *sd.ServerNode { return node.ServerNode })
 }
 
-func (this *HttpRpcClient) nodesGet(nodes map[uint32]*HttpRpcNode, path string, query url.Values, res interface{}, headers ...client.HttpHeader) MultiResults[*message.Reply] {
+func (this *HttpRpcClient) nodesGet(ctx context.Context, nodes map[uint32]*HttpRpcNode, path string, query url.Values, res interface{}, headers ...client.HttpHeader) MultiResults[*message.Reply] {
 	var fs []func() error
 	var ars = MultiResults[*message.Reply]{}
 	for _, node := range nodes {
@@ -143,7 +146,7 @@ func (this *HttpRpcClient) nodesGet(nodes map[uint32]*HttpRpcNode, path string,
 
 		ars[node.AppId] = ar
 		fs = append(fs, func() error {
-			ar.Res, ar.Err = curNode.Get(path, query, util.New(res), headers...)
+			ar.Res, ar.Err = curNode.get(ctx, path, query, util.New(res), headers...)
 			return ar.Err
 		})
 	}
@@ -153,15 +156,25 @@ func (this *HttpRpcClient) nodesGet(nodes map[uint32]*HttpRpcNode, path string,
 }
 
 func (this *HttpRpcClient) AllGet(service, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (MultiResults[*message.Reply], error) {
+	return this.AllGetContext(context.Background(), service, path, query, res, headers...)
+}
+
+// AllGetContext is AllGet with the trace context of ctx.
+func (this *HttpRpcClient) AllGetContext(ctx context.Context, service, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (MultiResults[*message.Reply], error) {
 	var nodes = this.Nodes(service)
 	if nodes == nil {
 		return nil, errors.Error("the node is unavailable")
 	}
 
-	return this.nodesGet(nodes, path, query, res, headers...), nil
+	return this.nodesGet(ctx, nodes, path, query, res, headers...), nil
 }
 
 func (this *HttpRpcClient) MultiGet(service string, appIds []uint32, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (MultiResults[*message.Reply], error) {
+	return this.MultiGetContext(context.Background(), service, appIds, path, query, res, headers...)
+}
+
+// MultiGetContext is MultiGet with the trace context of ctx.
+func (this *HttpRpcClient) MultiGetContext(ctx context.Context, service string, appIds []uint32, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (MultiResults[*message.Reply], error) {
 	var nodes = this.Nodes(service)
 	if nodes == nil {
 		return nil, errors.Error("the node is unavailable")
@@ -175,28 +188,50 @@ func (this *HttpRpcClient) MultiGet(service string, appIds []uint32, path string
 		}
 	}
 
-	return this.nodesGet(selectNodes, path, query, res, headers...), nil
+	return this.nodesGet(ctx, selectNodes, path, query, res, headers...), nil
 }
 
 func (this *HttpRpcClient) RandGet(service, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
-	var node = this.RandNode(service)
+	return this.RandGetContext(context.Background(), service, path, query, res, headers...)
+}
+
+// RandGetContext is RandGet with the trace context of ctx.
+func (this *HttpRpcClient) RandGetContext(ctx context.Context, service, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	return this.KeyGetContext(ctx, service, "", path, query, res, headers...)
+}
+
+// KeyGet sends the request to the node selected by the balancer of the service with the key.
+func (this *HttpRpcClient) KeyGet(service, key, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	return this.KeyGetContext(context.Background(), service, key, path, query, res, headers...)
+}
+
+// KeyGetContext is KeyGet with the trace context of ctx.
+func (this *HttpRpcClient) KeyGetContext(ctx context.Context, service, key, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	var balancer = this.getBalancer(service)
+	var node = selectNode(balancer, this.Nodes(service), key, func(node *HttpRpcNode) *sd.ServerNode { return node.ServerNode })
 	if node == nil {
 		return nil, errors.Error("do not have any available node")
 	}
 
-	return node.Get(path, query, res, headers...)
+	defer acquireNode(balancer, node.ServerNode)()
+	return node.get(ctx, path, query, res, headers...)
 }
 
 func (this *HttpRpcClient) AppIdGet(service string, appId uint32, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	return this.AppIdGetContext(context.Background(), service, appId, path, query, res, headers...)
+}
+
+// AppIdGetContext is AppIdGet with the trace context of ctx.
+func (this *HttpRpcClient) AppIdGetContext(ctx context.Context, service string, appId uint32, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
 	var node = this.Node(service, appId)
 	if node == nil {
 		return nil, errors.Error("the node is unavailable")
 	}
 
-	return node.Get(path, query, res, headers...)
+	return node.get(ctx, path, query, res, headers...)
 }
 
-func (this *HttpRpcClient) nodesPost(nodes map[uint32]*HttpRpcNode, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) MultiResults[*message.Reply] {
+func (this *HttpRpcClient) nodesPost(ctx context.Context, nodes map[uint32]*HttpRpcNode, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) MultiResults[*message.Reply] {
 	var fs []func() error
 	var ars = MultiResults[*message.Reply]{}
 	for id, node := range nodes {
@@ -205,7 +240,7 @@ func (this *HttpRpcClient) nodesPost(nodes map[uint32]*HttpRpcNode, path string,
 
 		ars[id] = ar
 		fs = append(fs, func() error {
-			ar.Res, ar.Err = curNode.Post(path, query, req, util.New(res), headers...)
+			ar.Res, ar.Err = curNode.post(ctx, path, query, req, util.New(res), headers...)
 			return ar.Err
 		})
 	}
@@ -215,15 +250,25 @@ func (this *HttpRpcClient) nodesPost(nodes map[uint32]*HttpRpcNode, path string,
 }
 
 func (this *HttpRpcClient) AllPost(service, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (MultiResults[*message.Reply], error) {
+	return this.AllPostContext(context.Background(), service, path, query, req, res, headers...)
+}
+
+// AllPostContext is AllPost with the trace context of ctx.
+func (this *HttpRpcClient) AllPostContext(ctx context.Context, service, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (MultiResults[*message.Reply], error) {
 	var nodes = this.Nodes(service)
 	if nodes == nil {
 		return nil, errors.Error("the node is unavailable")
 	}
 
-	return this.nodesPost(nodes, path, query, req, res, headers...), nil
+	return this.nodesPost(ctx, nodes, path, query, req, res, headers...), nil
 }
 
 func (this *HttpRpcClient) MultiPost(service string, appIds []uint32, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (MultiResults[*message.Reply], error) {
+	return this.MultiPostContext(context.Background(), service, appIds, path, query, req, res, headers...)
+}
+
+// MultiPostContext is MultiPost with the trace context of ctx.
+func (this *HttpRpcClient) MultiPostContext(ctx context.Context, service string, appIds []uint32, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (MultiResults[*message.Reply], error) {
 	var nodes = this.Nodes(service)
 	if nodes == nil {
 		return nil, errors.Error("the node is unavailable")
@@ -237,23 +282,45 @@ func (this *HttpRpcClient) MultiPost(service string, appIds []uint32, path strin
 		}
 	}
 
-	return this.nodesPost(selectNodes, path, query, req, res, headers...), nil
+	return this.nodesPost(ctx, selectNodes, path, query, req, res, headers...), nil
 }
 
 func (this *HttpRpcClient) RandPost(service, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
-	var node = this.RandNode(service)
+	return this.RandPostContext(context.Background(), service, path, query, req, res, headers...)
+}
+
+// RandPostContext is RandPost with the trace context of ctx.
+func (this *HttpRpcClient) RandPostContext(ctx context.Context, service, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	return this.KeyPostContext(ctx, service, "", path, query, req, res, headers...)
+}
+
+// KeyPost sends the request to the node selected by the balancer of the service with the key.
+func (this *HttpRpcClient) KeyPost(service, key, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	return this.KeyPostContext(context.Background(), service, key, path, query, req, res, headers...)
+}
+
+// KeyPostContext is KeyPost with the trace context of ctx.
+func (this *HttpRpcClient) KeyPostContext(ctx context.Context, service, key, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	var balancer = this.getBalancer(service)
+	var node = selectNode(balancer, this.Nodes(service), key, func(node *HttpRpcNode) *sd.ServerNode { return node.ServerNode })
 	if node == nil {
 		return nil, errors.Error("do not have any available node")
 	}
 
-	return node.Post(path, query, req, res, headers...)
+	defer acquireNode(balancer, node.ServerNode)()
+	return node.post(ctx, path, query, req, res, headers...)
 }
 
 func (this *HttpRpcClient) AppIdPost(service string, appId uint32, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	return this.AppIdPostContext(context.Background(), service, appId, path, query, req, res, headers...)
+}
+
+// AppIdPostContext is AppIdPost with the trace context of ctx.
+func (this *HttpRpcClient) AppIdPostContext(ctx context.Context, service string, appId uint32, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
 	var node = this.Node(service, appId)
 	if node == nil {
 		return nil, errors.Error("the node is unavailable")
 	}
 
-	return node.Post(path, query, req, res, headers...)
+	return node.post(ctx, path, query, req, res, headers...)
 }
@@ -1,26 +1,441 @@
 package rpc
 
 import (
+	"context"
 	"github.com/oylshe1314/framework/client"
 	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/http/ws"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/util"
+	"sync"
 )
 
+const defaultWebSocketPattern = "/"
+
 type WebSocketRpcNode struct {
 	*sd.ServerNode
 	*client.WebSocketClient
 }
 
 type WebSocketRpcClient struct {
+	balancers
+
+	closed bool
+
+	logger  log.Logger
+	codec   message.Codec
+	pattern string
+	locker  sync.RWMutex
+	nodes   map[string]map[uint32]*WebSocketRpcNode
+
+	workChan chan *WebSocketRpcNode
+
+	connectHandlers    map[string]func(node *sd.ServerNode, conn *ws.Conn)
+	disconnectHandlers map[string]func(node *sd.ServerNode, conn *ws.Conn)
+	messageHandlers    map[string]map[uint32]func(node *sd.ServerNode, msg *ws.Message)
+	defaultHandlers    map[string]func(node *sd.ServerNode, msg *ws.Message)
+}
+
+func (this *WebSocketRpcClient) SetLogger(logger log.Logger) {
+	this.logger = logger
+}
+
+func (this *WebSocketRpcClient) SetCodec(codec message.Codec) {
+	this.codec = codec
+}
+
+// SetPattern sets the default upgrade pattern, the 'pattern' of the inner network extra of a node overrides it.
+func (this *WebSocketRpcClient) SetPattern(pattern string) {
+	this.pattern = pattern
 }
 
 func (this *WebSocketRpcClient) Init() error {
-	panic("implement me")
+	if this.logger == nil {
+		this.logger = log.DefaultLogger
+	}
+
+	if len(this.pattern) == 0 {
+		this.pattern = defaultWebSocketPattern
+	}
+
+	this.nodes = map[string]map[uint32]*WebSocketRpcNode{}
+
+	this.connectHandlers = map[string]func(node *sd.ServerNode, conn *ws.Conn){}
+	this.disconnectHandlers = map[string]func(node *sd.ServerNode, conn *ws.Conn){}
+	this.messageHandlers = map[string]map[uint32]func(node *sd.ServerNode, msg *ws.Message){}
+	this.defaultHandlers = map[string]func(node *sd.ServerNode, msg *ws.Message){}
+
+	return nil
 }
 
 func (this *WebSocketRpcClient) Close() error {
-	panic("implement me")
+	this.locker.Lock()
+	if this.closed {
+		this.locker.Unlock()
+		return nil
+	}
+	this.closed = true
+
+	if this.workChan != nil {
+		close(this.workChan)
+	}
+
+	var closing []*WebSocketRpcNode
+	for _, nodes := range this.nodes {
+		for _, node := range nodes {
+			closing = append(closing, node)
+		}
+	}
+	this.locker.Unlock()
+
+	for _, node := range closing {
+		_ = node.Close()
+	}
+	return nil
 }
 
 func (this *WebSocketRpcClient) Work() error {
-	panic("implement me")
+	this.locker.Lock()
+	if this.closed || this.workChan != nil {
+		this.locker.Unlock()
+		return nil
+	}
+	var workChan = make(chan *WebSocketRpcNode, 64)
+	this.workChan = workChan
+	this.locker.Unlock()
+
+	for node := range workChan {
+		var gotoWork = false
+
+		var connectHandler = this.connectHandlers[node.Name]
+		if connectHandler != nil {
+			gotoWork = true
+			node.WsConnectHandler(func(conn *ws.Conn) {
+				connectHandler(node.ServerNode, conn)
+			})
+		}
+
+		var disconnectHandler = this.disconnectHandlers[node.Name]
+		if disconnectHandler != nil {
+			gotoWork = true
+			node.WsDisconnectHandler(func(conn *ws.Conn) {
+				disconnectHandler(node.ServerNode, conn)
+			})
+		}
+
+		var messageHandlers = this.messageHandlers[node.Name]
+		if messageHandlers != nil {
+			gotoWork = true
+			for id, handler := range messageHandlers {
+				modId, msgId := util.Split2uint16(id)
+				node.WsMessageHandler(modId, msgId, func(msg *ws.Message) {
+					handler(node.ServerNode, msg)
+				})
+			}
+		}
+
+		var defaultHandler = this.defaultHandlers[node.Name]
+		if defaultHandler != nil {
+			gotoWork = true
+			node.WsDefaultHandler(func(msg *ws.Message) {
+				defaultHandler(node.ServerNode, msg)
+			})
+		}
+
+		if gotoWork {
+			go func(node *WebSocketRpcNode) {
+				var err = node.Work()
+				if err != nil {
+					return
+				}
+			}(node)
+		}
+	}
+	return nil
+}
+
+// work passes the node to Work to serve its connection, the nodes are ignored before Work or after closing.
+func (this *WebSocketRpcClient) work(node *WebSocketRpcNode) {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	if !this.closed && this.workChan != nil {
+		this.workChan <- node
+	}
+}
+
+func (this *WebSocketRpcClient) ConnectHandler(service string, handler func(node *sd.ServerNode, conn *ws.Conn)) {
+	this.connectHandlers[service] = handler
+}
+
+func (this *WebSocketRpcClient) DisconnectHandler(service string, handler func(node *sd.ServerNode, conn *ws.Conn)) {
+	this.disconnectHandlers[service] = handler
+}
+
+func (this *WebSocketRpcClient) MessageHandler(service string, modId, msgId uint16, handler func(node *sd.ServerNode, msg *ws.Message)) {
+	var messageHandlers = this.messageHandlers[service]
+	if messageHandlers == nil {
+		messageHandlers = map[uint32]func(node *sd.ServerNode, msg *ws.Message){}
+		this.messageHandlers[service] = messageHandlers
+	}
+	messageHandlers[util.Compose2uint16(modId, msgId)] = handler
+}
+
+func (this *WebSocketRpcClient) DefaultHandler(service string, handler func(node *sd.ServerNode, msg *ws.Message)) {
+	this.defaultHandlers[service] = handler
+}
+
+func (this *WebSocketRpcClient) nodePattern(node *sd.ServerNode) string {
+	if node.Inner.Extra != nil {
+		pattern, ok := node.Inner.Extra["pattern"].(string)
+		if ok && len(pattern) > 0 {
+			return pattern
+		}
+	}
+	return this.pattern
+}
+
+func (this *WebSocketRpcClient) SubscribeCallback(service string, nodes []*sd.ServerNode) {
+	if len(nodes) == 0 {
+		this.locker.Lock()
+		var oldNodes = this.nodes[service]
+		delete(this.nodes, service)
+		this.locker.Unlock()
+
+		for _, rn := range oldNodes {
+			_ = rn.Close()
+		}
+
+		this.logger.Warn("The service subscribe callback received an empty nodes list, service: ", service)
+	} else {
+		this.locker.Lock()
+		var oldNodes = this.nodes[service]
+		this.locker.Unlock()
+
+		var clients = make(map[uint32]*WebSocketRpcNode)
+		for _, node := range nodes {
+			if node.Inner == nil {
+				this.logger.Warnf("The inner network information of the service node is nil, service: %s, appId: %d", service, node.AppId)
+				continue
+			}
+
+			var pattern = this.nodePattern(node)
+			if oldNodes != nil {
+				oldNode := oldNodes[node.AppId]
+				if oldNode != nil && oldNode.Inner.Network == node.Inner.Network && oldNode.Inner.Address == node.Inner.Address && this.nodePattern(oldNode.ServerNode) == pattern {
+					clients[node.AppId] = &WebSocketRpcNode{ServerNode: node, WebSocketClient: oldNode.WebSocketClient}
+					continue
+				}
+			}
+
+			var wsClient = &client.WebSocketClient{}
+			wsClient.WithNetwork(node.Inner.Network)
+			wsClient.WithAddress(node.Inner.Address)
+			wsClient.SetLogger(this.logger)
+			wsClient.SetCodec(this.codec)
+
+			var err = wsClient.Init()
+			if err != nil {
+				this.logger.Errorf("Init the service node failed, service: %s, appId: %d, error: %v", service, node.AppId, err)
+				continue
+			}
+
+			err = wsClient.Dial(pattern)
+			if err != nil {
+				this.logger.Errorf("Dial the service node failed, service: %s, appId: %d, error: %v", service, node.AppId, err)
+				continue
+			}
+
+			this.logger.Infof("Init the service node succeed, service: %s, appId: %d, address: %s", service, node.AppId, node.Inner.Address)
+
+			var rpcNode = &WebSocketRpcNode{ServerNode: node, WebSocketClient: wsClient}
+
+			clients[node.AppId] = rpcNode
+
+			this.work(rpcNode)
+		}
+
+		this.locker.Lock()
+		this.nodes[service] = clients
+		this.locker.Unlock()
+
+		var removed []*WebSocketRpcNode
+		for appId, oldNode := range oldNodes {
+			var newNode = clients[appId]
+			if newNode == nil || newNode.WebSocketClient != oldNode.WebSocketClient {
+				removed = append(removed, oldNode)
+			}
+		}
+
+		for _, rn := range removed {
+			_ = rn.Close()
+			this.logger.Infof("The service node was closed, service: %s, appId: %d, address: %s", service, rn.AppId, rn.Inner.Address)
+		}
+	}
+}
+
+func (this *WebSocketRpcClient) Servers() []string {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return util.MapKeys(this.nodes)
+}
+
+func (this *WebSocketRpcClient) Nodes(service string) map[uint32]*WebSocketRpcNode {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return this.nodes[service]
+}
+
+func (this *WebSocketRpcClient) Node(service string, appId uint32) *WebSocketRpcNode {
+	var nodes = this.Nodes(service)
+	if nodes == nil {
+		return nil
+	}
+	return nodes[appId]
+}
+
+// RandNode returns the node selected by the balancer of the service without a key, it's random by default.
+func (this *WebSocketRpcClient) RandNode(service string) *WebSocketRpcNode {
+	return this.KeyNode(service, "")
+}
+
+func (this *WebSocketRpcClient) KeyNode(service, key string) *WebSocketRpcNode {
+	return selectNode(this.getBalancer(service), this.Nodes(service), key, func(node *WebSocketRpcNode) *sd.ServerNode { return node.ServerNode })
+}
+
+func (this *WebSocketRpcClient) nodesSend(ctx context.Context, nodes map[uint32]*WebSocketRpcNode, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
+	var result = MultiResults[any]{}
+	for _, node := range nodes {
+		result[node.AppId] = &MultiResult[any]{Err: node.send(ctx, modId, msgId, v)}
+	}
+	return result, nil
+}
+
+func (this *WebSocketRpcClient) AllSend(service string, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
+	return this.AllSendContext(context.Background(), service, modId, msgId, v)
+}
+
+// AllSendContext is AllSend with the trace context of ctx.
+func (this *WebSocketRpcClient) AllSendContext(ctx context.Context, service string, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
+	var nodes = this.Nodes(service)
+	if nodes == nil {
+		return nil, errors.Error("the node is unavailable")
+	}
+	return this.nodesSend(ctx, nodes, modId, msgId, v)
+}
+
+func (this *WebSocketRpcClient) MultiSend(service string, appIds []uint32, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
+	return this.MultiSendContext(context.Background(), service, appIds, modId, msgId, v)
+}
+
+// MultiSendContext is MultiSend with the trace context of ctx.
+func (this *WebSocketRpcClient) MultiSendContext(ctx context.Context, service string, appIds []uint32, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
+	var nodes = this.Nodes(service)
+	if nodes == nil {
+		return nil, errors.Error("the node is unavailable")
+	}
+
+	var selectNodes = map[uint32]*WebSocketRpcNode{}
+	for _, appId := range appIds {
+		var node = nodes[appId]
+		if node != nil {
+			selectNodes[appId] = node
+		}
+	}
+	return this.nodesSend(ctx, selectNodes, modId, msgId, v)
+}
+
+func (this *WebSocketRpcClient) RandSend(service string, modId, msgId uint16, v interface{}) error {
+	return this.RandSendContext(context.Background(), service, modId, msgId, v)
+}
+
+// RandSendContext is RandSend with the trace context of ctx.
+func (this *WebSocketRpcClient) RandSendContext(ctx context.Context, service string, modId, msgId uint16, v interface{}) error {
+	var node = this.RandNode(service)
+	if node == nil {
+		return errors.Error("the node is unavailable")
+	}
+
+	return node.send(ctx, modId, msgId, v)
+}
+
+// KeySend sends the message to the node selected by the balancer of the service with the key.
+func (this *WebSocketRpcClient) KeySend(service, key string, modId, msgId uint16, v interface{}) error {
+	return this.KeySendContext(context.Background(), service, key, modId, msgId, v)
+}
+
+// KeySendContext is KeySend with the trace context of ctx.
+func (this *WebSocketRpcClient) KeySendContext(ctx context.Context, service, key string, modId, msgId uint16, v interface{}) error {
+	var node = this.KeyNode(service, key)
+	if node == nil {
+		return errors.Error("the node is unavailable")
+	}
+
+	return node.send(ctx, modId, msgId, v)
+}
+
+func (this *WebSocketRpcClient) AppIdSend(service string, appId uint32, modId, msgId uint16, v interface{}) error {
+	return this.AppIdSendContext(context.Background(), service, appId, modId, msgId, v)
+}
+
+// AppIdSendContext is AppIdSend with the trace context of ctx.
+func (this *WebSocketRpcClient) AppIdSendContext(ctx context.Context, service string, appId uint32, modId, msgId uint16, v interface{}) error {
+	var node = this.Node(service, appId)
+	if node == nil {
+		return errors.Error("the node is unavailable")
+	}
+
+	return node.send(ctx, modId, msgId, v)
+}
+
+func (this *WebSocketRpcClient) nodesRead(nodes map[uint32]*WebSocketRpcNode) MultiResults[*ws.Message] {
+	var fs []func() error
+	var ars = MultiResults[*ws.Message]{}
+	for _, node := range nodes {
+		var curNode = node
+		var ar = &MultiResult[*ws.Message]{}
+
+		ars[node.AppId] = ar
+		fs = append(fs, func() error {
+			ar.Res, ar.Err = curNode.Read()
+			return ar.Err
+		})
+	}
+
+	util.WaitAll(fs...)
+	return ars
+}
+
+func (this *WebSocketRpcClient) AllRead(service string) (MultiResults[*ws.Message], error) {
+	var nodes = this.Nodes(service)
+	if nodes == nil {
+		return nil, errors.Error("the node is unavailable")
+	}
+	return this.nodesRead(nodes), nil
+}
+
+func (this *WebSocketRpcClient) MultiRead(service string, appIds []uint32) (MultiResults[*ws.Message], error) {
+	var nodes = this.Nodes(service)
+	if nodes == nil {
+		return nil, errors.Error("the node is unavailable")
+	}
+
+	var selectNodes = map[uint32]*WebSocketRpcNode{}
+	for _, appId := range appIds {
+		var node = nodes[appId]
+		if node != nil {
+			selectNodes[appId] = node
+		}
+	}
+	return this.nodesRead(selectNodes), nil
+}
+
+func (this *WebSocketRpcClient) AppIdRead(service string, appId uint32) (*ws.Message, error) {
+	var node = this.Node(service, appId)
+	if node == nil {
+		return nil, errors.Error("the node is unavailable")
+	}
+	return node.Read()
 }
@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/http/ws"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/message"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newWebSocketServer(t *testing.T) *httptest.Server {
+	var serverMux = &ws.ConnMux{}
+	serverMux.SetCodec(message.NewJsonCodec())
+	serverMux.WsMessageHandler(1, 1, func(msg *ws.Message) {
+		var req string
+		if err := msg.Read(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		_ = msg.Conn.Send(1, 2, "re: "+req)
+	})
+
+	var upgrader websocket.Upgrader
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wc, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = ws.NewConn(wc, log.DefaultLogger, serverMux).Serve()
+	}))
+}
+
+func TestWebSocketRpcClient(t *testing.T) {
+	var svr = newWebSocketServer(t)
+	defer svr.Close()
+
+	var rpcClient = &WebSocketRpcClient{}
+	rpcClient.SetCodec(message.NewJsonCodec())
+	if err := rpcClient.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies = make(chan string, 1)
+	rpcClient.MessageHandler("test", 1, 2, func(node *sd.ServerNode, msg *ws.Message) {
+		var res string
+		_ = msg.Read(&res)
+		replies <- res
+	})
+
+	var worked = make(chan struct{})
+	go func() {
+		_ = rpcClient.Work()
+		close(worked)
+	}()
+
+	// the nodes subscribed before Work are not served
+	for {
+		rpcClient.locker.RLock()
+		var working = rpcClient.workChan != nil
+		rpcClient.locker.RUnlock()
+		if working {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var nodes = []*sd.ServerNode{{Name: "test", AppId: 1, Inner: &sd.ServerNetwork{Network: "tcp", Address: svr.URL}}}
+	rpcClient.SubscribeCallback("test", nodes)
+	var node = rpcClient.Node("test", 1)
+	if node == nil {
+		t.Fatal("the node was not subscribed")
+	}
+
+	if err := rpcClient.AppIdSend("test", 1, 1, 1, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case res := <-replies:
+		if res != "re: hello" {
+			t.Fatalf("unexpected reply: %s", res)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("the reply was not handled")
+	}
+
+	rpcClient.SubscribeCallback("test", nodes)
+	if rpcClient.Node("test", 1).WebSocketClient != node.WebSocketClient {
+		t.Fatal("the connection of the unchanged node should be kept")
+	}
+
+	rpcClient.SubscribeCallback("test", nil)
+	if rpcClient.Node("test", 1) != nil {
+		t.Fatal("the node was not removed")
+	}
+	if err := rpcClient.AppIdSend("test", 1, 1, 1, "hello"); err == nil {
+		t.Fatal("sending to a removed node should fail")
+	}
+
+	_ = rpcClient.Close()
+	select {
+	case <-worked:
+	case <-time.After(time.Second * 3):
+		t.Fatal("Work did not return after closing")
+	}
+}
+
+func TestWebSocketRpcClientClose(t *testing.T) {
+	var rpcClient = &WebSocketRpcClient{}
+	if err := rpcClient.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { _ = rpcClient.Close() }()
+	_ = rpcClient.Work()
+	_ = rpcClient.Close()
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"context"
+	"github.com/oylshe1314/framework/client"
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/metrics"
+	"github.com/oylshe1314/framework/util"
+	"net/url"
+)
+
+var callsTotal = metrics.NewCounter("rpc_calls_total", "The total rpc calls by the service, the node and the result, ok or error.", "client", "service", "node", "result")
+
+func observeCall(kind string, node *sd.ServerNode, err error) {
+	callsTotal.Inc(kind, node.Name, util.IntegerToString(node.AppId), util.If(err == nil, "ok", "error"))
+}
+
+func (this *HttpRpcNode) get(ctx context.Context, path string, query url.Values, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	reply, err := this.GetContext(ctx, path, query, res, headers...)
+	observeCall("http", this.ServerNode, err)
+	return reply, err
+}
+
+func (this *HttpRpcNode) post(ctx context.Context, path string, query url.Values, req, res interface{}, headers ...client.HttpHeader) (*message.Reply, error) {
+	reply, err := this.PostContext(ctx, path, query, req, res, headers...)
+	observeCall("http", this.ServerNode, err)
+	return reply, err
+}
+
+func (this *NetRpcNode) send(ctx context.Context, modId, msgId uint16, v interface{}) error {
+	var err = this.SendContext(ctx, modId, msgId, v)
+	observeCall("net", this.ServerNode, err)
+	return err
+}
+
+func (this *NetRpcNode) call(ctx context.Context, modId, msgId uint16, req, res interface{}) error {
+	if !this.served {
+		return errNodeNotServed
+	}
+
+	var err = this.Call(ctx, modId, msgId, req, res)
+	observeCall("net", this.ServerNode, err)
+	return err
+}
+
+func (this *WebSocketRpcNode) send(ctx context.Context, modId, msgId uint16, v interface{}) error {
+	var err = this.SendContext(ctx, modId, msgId, v)
+	observeCall("ws", this.ServerNode, err)
+	return err
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"github.com/oylshe1314/framework/client"
 	"github.com/oylshe1314/framework/client/sd"
 	"github.com/oylshe1314/framework/errors"
@@ -11,16 +12,24 @@ import (
 	"sync"
 )
 
+var errNodeNotServed = errors.Error("the node is not served, the rpc client was not working when it was subscribed")
+
 type NetRpcNode struct {
 	*sd.ServerNode
 	*client.NetClient
+
+	// served is whether the connection is served by Work, the replies of the calls are dispatched by it.
+	served bool
 }
 
 type NetRpcClient struct {
+	balancers
+
 	closed bool
 
 	logger log.Logger
 	codec  message.Codec
+	policy *client.ReconnectPolicy
 	locker sync.RWMutex
 	nodes  map[string]map[uint32]*NetRpcNode
 
@@ -40,6 +49,12 @@ func (this *NetRpcClient) SetCodec(codec message.Codec) {
 	this.codec = codec
 }
 
+// SetReconnectPolicy makes the nodes reconnect by the policy when their connections dropped,
+// a node is removed when it gave up reconnecting.
+func (this *NetRpcClient) SetReconnectPolicy(policy *client.ReconnectPolicy) {
+	this.policy = policy
+}
+
 func (this *NetRpcClient) Init() error {
 	if this.logger == nil {
 		this.logger = log.DefaultLogger
@@ -56,45 +71,59 @@ func (this *NetRpcClient) Init() error {
 }
 
 func (this *NetRpcClient) Close() error {
+	this.locker.Lock()
+	if this.closed {
+		this.locker.Unlock()
+		return nil
+	}
 	this.closed = true
 
 	if this.workChan != nil {
 		close(this.workChan)
 	}
 
+	var closing []*NetRpcNode
 	for _, nodes := range this.nodes {
 		for _, node := range nodes {
-			_ = node.Close()
+			closing = append(closing, node)
 		}
 	}
+	this.locker.Unlock()
+
+	for _, node := range closing {
+		_ = node.Close()
+	}
 	return nil
 }
 
-func (this *NetRpcClient) Work() error {
-	this.workChan = make(chan *NetRpcNode, 64)
-	for {
-		if this.closed {
-			return nil
-		}
-
-		var node, ok = <-this.workChan
-		if !ok {
-			return nil
-		}
+func (this *NetRpcClient) isClosed() bool {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return this.closed
+}
 
-		var gotoWork = false
+// Work serves the connections of the nodes subscribed after it started, every node is served so that the
+// replies of the calls are dispatched even if the service has no handlers.
+func (this *NetRpcClient) Work() error {
+	this.locker.Lock()
+	if this.closed || this.workChan != nil {
+		this.locker.Unlock()
+		return nil
+	}
+	var workChan = make(chan *NetRpcNode, 64)
+	this.workChan = workChan
+	this.locker.Unlock()
 
+	for node := range workChan {
 		var connectHandler = this.connectHandlers[node.Name]
 		if connectHandler != nil {
-			gotoWork = true
 			node.ConnectHandler(func(conn *net.Conn) {
 				connectHandler(node.ServerNode, conn)
 			})
 		}
 
-		var disconnectHandler = this.connectHandlers[node.Name]
+		var disconnectHandler = this.disconnectHandlers[node.Name]
 		if disconnectHandler != nil {
-			gotoWork = true
 			node.DisconnectHandler(func(conn *net.Conn) {
 				disconnectHandler(node.ServerNode, conn)
 			})
@@ -102,7 +131,6 @@ func (this *NetRpcClient) Work() error {
 
 		var messageHandlers = this.messageHandlers[node.Name]
 		if messageHandlers != nil {
-			gotoWork = true
 			for id, handler := range messageHandlers {
 				modId, msgId := util.Split2uint16(id)
 				node.MessageHandler(modId, msgId, func(msg *net.Message) {
@@ -113,20 +141,32 @@ func (this *NetRpcClient) Work() error {
 
 		var defaultHandler = this.defaultHandlers[node.Name]
 		if defaultHandler != nil {
-			gotoWork = true
 			node.DefaultHandler(func(msg *net.Message) {
 				defaultHandler(node.ServerNode, msg)
 			})
 		}
 
-		if gotoWork {
-			go func(node *NetRpcNode) {
-				var err = node.Work()
-				if err != nil {
-					return
-				}
-			}(node)
-		}
+		go func(node *NetRpcNode) {
+			var err = node.Work()
+			if this.isClosed() || this.policy == nil {
+				return
+			}
+			if this.removeNode(node) {
+				this.logger.Warnf("The service node was removed, service: %s, appId: %d, error: %v", node.Name, node.AppId, err)
+			}
+		}(node)
+	}
+	return nil
+}
+
+// work passes the node to Work to serve its connection, the nodes are not served before Work or after closing.
+func (this *NetRpcClient) work(node *NetRpcNode) {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	if !this.closed && this.workChan != nil {
+		node.served = true
+		this.workChan <- node
 	}
 }
 
@@ -173,7 +213,7 @@ func (this *NetRpcClient) SubscribeCallback(service string, nodes []*sd.ServerNo
 			if oldNodes != nil {
 				oldNode := oldNodes[node.AppId]
 				if oldNode != nil && oldNode.Inner.Network == node.Inner.Network && oldNode.Inner.Address == node.Inner.Address {
-					clients[node.AppId] = &NetRpcNode{ServerNode: node, NetClient: oldNode.NetClient}
+					clients[node.AppId] = &NetRpcNode{ServerNode: node, NetClient: oldNode.NetClient, served: oldNode.served}
 					continue
 				}
 			}
@@ -183,6 +223,7 @@ func (this *NetRpcClient) SubscribeCallback(service string, nodes []*sd.ServerNo
 			netClient.WithAddress(node.Inner.Address)
 			netClient.SetLogger(this.logger)
 			netClient.SetCodec(this.codec)
+			netClient.SetReconnectPolicy(this.policy)
 
 			var err = netClient.Init()
 			if err != nil {
@@ -202,9 +243,7 @@ func (this *NetRpcClient) SubscribeCallback(service string, nodes []*sd.ServerNo
 
 			clients[node.AppId] = rpcNode
 
-			if this.workChan != nil {
-				this.workChan <- rpcNode
-			}
+			this.work(rpcNode)
 		}
 
 		this.locker.Lock()
@@ -225,6 +264,26 @@ func (this *NetRpcClient) SubscribeCallback(service string, nodes []*sd.ServerNo
 	}
 }
 
+func (this *NetRpcClient) removeNode(node *NetRpcNode) bool {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var nodes = this.nodes[node.Name]
+	var curNode = nodes[node.AppId]
+	if curNode == nil || curNode.NetClient != node.NetClient {
+		return false
+	}
+
+	var newNodes = make(map[uint32]*NetRpcNode, len(nodes))
+	for appId, n := range nodes {
+		if appId != node.AppId {
+			newNodes[appId] = n
+		}
+	}
+	this.nodes[node.Name] = newNodes
+	return true
+}
+
 func (this *NetRpcClient) Servers() []string {
 	this.locker.RLock()
 	defer this.locker.RUnlock()
@@ -245,32 +304,42 @@ func (this *NetRpcClient) Node(service string, appId uint32) *NetRpcNode {
 	return nodes[appId]
 }
 
+// RandNode returns the node selected by the balancer of the service without a key, it's random by default.
 func (this *NetRpcClient) RandNode(service string) *NetRpcNode {
-	var nodes = util.MapValues(this.Nodes(service))
-	if len(nodes) == 0 {
-		return nil
-	}
+	return this.KeyNode(service, "")
+}
 
-	return nodes[util.NewRand().IntN(len(nodes))]
+func (this *NetRpcClient) KeyNode(service, key string) *NetRpcNode {
+	return selectNode(this.getBalancer(service), this.Nodes(service), key, func(node *NetRpcNode) *sd.ServerNode { return node.ServerNode })
 }
 
-func (this *NetRpcClient) nodesSend(nodes map[uint32]*NetRpcNode, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
+func (this *NetRpcClient) nodesSend(ctx context.Context, nodes map[uint32]*NetRpcNode, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
 	var result = MultiResults[any]{}
 	for _, node := range nodes {
-		result[node.AppId] = &MultiResult[any]{Err: node.Send(modId, msgId, v)}
+		result[node.AppId] = &MultiResult[any]{Err: node.send(ctx, modId, msgId, v)}
 	}
 	return result, nil
 }
 
 func (this *NetRpcClient) AllSend(service string, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
+	return this.AllSendContext(context.Background(), service, modId, msgId, v)
+}
+
+// AllSendContext is AllSend with the trace context of ctx.
+func (this *NetRpcClient) AllSendContext(ctx context.Context, service string, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
 	var nodes = this.Nodes(service)
 	if nodes == nil {
 		return nil, errors.Error("the node is unavailable")
 	}
-	return this.nodesSend(nodes, modId, msgId, v)
+	return this.nodesSend(ctx, nodes, modId, msgId, v)
 }
 
 func (this *NetRpcClient) MultiSend(service string, appIds []uint32, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
+	return this.MultiSendContext(context.Background(), service, appIds, modId, msgId, v)
+}
+
+// MultiSendContext is MultiSend with the trace context of ctx.
+func (this *NetRpcClient) MultiSendContext(ctx context.Context, service string, appIds []uint32, modId, msgId uint16, v interface{}) (MultiResults[any], error) {
 	var nodes = this.Nodes(service)
 	if nodes == nil {
 		return nil, errors.Error("the node is unavailable")
@@ -283,25 +352,75 @@ func (this *NetRpcClient) MultiSend(service string, appIds []uint32, modId, msgI
 			selectNodes[appId] = node
 		}
 	}
-	return this.nodesSend(this.Nodes(service), modId, msgId, v)
+	return this.nodesSend(ctx, selectNodes, modId, msgId, v)
 }
 
 func (this *NetRpcClient) RandSend(service string, modId, msgId uint16, v interface{}) error {
+	return this.RandSendContext(context.Background(), service, modId, msgId, v)
+}
+
+// RandSendContext is RandSend with the trace context of ctx.
+func (this *NetRpcClient) RandSendContext(ctx context.Context, service string, modId, msgId uint16, v interface{}) error {
 	var node = this.RandNode(service)
 	if node == nil {
 		return errors.Error("the node is unavailable")
 	}
 
-	return node.Send(modId, msgId, v)
+	return node.send(ctx, modId, msgId, v)
+}
+
+// KeySend sends the message to the node selected by the balancer of the service with the key.
+func (this *NetRpcClient) KeySend(service, key string, modId, msgId uint16, v interface{}) error {
+	return this.KeySendContext(context.Background(), service, key, modId, msgId, v)
+}
+
+// KeySendContext is KeySend with the trace context of ctx.
+func (this *NetRpcClient) KeySendContext(ctx context.Context, service, key string, modId, msgId uint16, v interface{}) error {
+	var node = this.KeyNode(service, key)
+	if node == nil {
+		return errors.Error("the node is unavailable")
+	}
+
+	return node.send(ctx, modId, msgId, v)
 }
 
 func (this *NetRpcClient) AppIdSend(service string, appId uint32, modId, msgId uint16, v interface{}) error {
+	return this.AppIdSendContext(context.Background(), service, appId, modId, msgId, v)
+}
+
+// AppIdSendContext is AppIdSend with the trace context of ctx.
+func (this *NetRpcClient) AppIdSendContext(ctx context.Context, service string, appId uint32, modId, msgId uint16, v interface{}) error {
+	var node = this.Node(service, appId)
+	if node == nil {
+		return errors.Error("the node is unavailable")
+	}
+
+	return node.send(ctx, modId, msgId, v)
+}
+
+func (this *NetRpcClient) RandCall(ctx context.Context, service string, modId, msgId uint16, req, res interface{}) error {
+	return this.KeyCall(ctx, service, "", modId, msgId, req, res)
+}
+
+// KeyCall calls the node selected by the balancer of the service with the key.
+func (this *NetRpcClient) KeyCall(ctx context.Context, service, key string, modId, msgId uint16, req, res interface{}) error {
+	var balancer = this.getBalancer(service)
+	var node = selectNode(balancer, this.Nodes(service), key, func(node *NetRpcNode) *sd.ServerNode { return node.ServerNode })
+	if node == nil {
+		return errors.Error("the node is unavailable")
+	}
+
+	defer acquireNode(balancer, node.ServerNode)()
+	return node.call(ctx, modId, msgId, req, res)
+}
+
+func (this *NetRpcClient) AppIdCall(ctx context.Context, service string, appId uint32, modId, msgId uint16, req, res interface{}) error {
 	var node = this.Node(service, appId)
 	if node == nil {
 		return errors.Error("the node is unavailable")
 	}
 
-	return node.Send(modId, msgId, v)
+	return node.call(ctx, modId, msgId, req, res)
 }
 
 func (this *NetRpcClient) nodesRead(nodes map[uint32]*NetRpcNode) MultiResults[*net.Message] {
@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"context"
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/net"
+	stdnet "net"
+	"testing"
+	"time"
+)
+
+func newNetServer(t *testing.T) stdnet.Listener {
+	l, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var serverMux = &net.ConnMux{}
+	serverMux.SetCodec(message.NewJsonCodec())
+	serverMux.MessageHandler(1, 1, func(msg *net.Message) {
+		var req string
+		_ = msg.Read(&req)
+		_ = msg.Reply("re: " + req)
+	})
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go net.NewConn(c, log.DefaultLogger, serverMux).Serve()
+		}
+	}()
+	return l
+}
+
+func TestNetRpcClientCall(t *testing.T) {
+	var l = newNetServer(t)
+	defer l.Close()
+
+	var nodes = []*sd.ServerNode{{Name: "test", AppId: 1, Inner: &sd.ServerNetwork{Network: "tcp", Address: l.Addr().String()}}}
+
+	var rpcClient = &NetRpcClient{}
+	rpcClient.SetCodec(message.NewJsonCodec())
+	if err := rpcClient.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer rpcClient.Close()
+
+	// the node subscribed before Work is not served, the call fails instead of blocking
+	rpcClient.SubscribeCallback("test", nodes)
+	var res string
+	if err := rpcClient.RandCall(context.Background(), "test", 1, 1, "hello", &res); err != errNodeNotServed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rpcClient.SubscribeCallback("test", nil)
+
+	go func() { _ = rpcClient.Work() }()
+	for {
+		rpcClient.locker.RLock()
+		var working = rpcClient.workChan != nil
+		rpcClient.locker.RUnlock()
+		if working {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// the service has neither handlers nor a reconnect policy
+	rpcClient.SubscribeCallback("test", nodes)
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	if err := rpcClient.RandCall(ctx, "test", 1, 1, "hello", &res); err != nil {
+		t.Fatal(err)
+	}
+	if res != "re: hello" {
+		t.Fatalf("unexpected reply: %s", res)
+	}
+}
@@ -0,0 +1,106 @@
+package etcd
+
+import (
+	"context"
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/server"
+	"time"
+)
+
+const DefaultTimeout = time.Millisecond * 5000
+const DefaultTTL int64 = 10
+
+const (
+	defaultRootPath  = "/sk.org/server"
+	serviceNodesPath = "/nodes/"
+)
+
+type client struct {
+	config *sd.Config
+
+	kv     KV
+	logger log.Logger
+	server server.Server
+
+	rootPath string
+	timeout  time.Duration
+	ttl      int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (this *client) SetServer(svr server.Server) {
+	this.server = svr
+}
+
+func (this *client) Init() error {
+	if this.config == nil {
+		return errors.Error("Service register-discovery client init 'config' can not be nil")
+	}
+
+	if this.kv == nil && len(this.config.Servers) == 0 {
+		return errors.Error("Service register-discovery client init 'config.Servers' can not be empty")
+	}
+
+	if this.server == nil {
+		this.logger = log.DefaultLogger
+	} else {
+		this.logger = this.server.Logger()
+	}
+
+	var ok bool
+	this.rootPath, ok = this.config.Extra["rootPath"].(string)
+	if !ok || this.rootPath == "" {
+		this.rootPath = defaultRootPath
+	} else {
+		if this.rootPath[len(this.rootPath)-1] == '/' {
+			this.rootPath = this.rootPath[:len(this.rootPath)-1]
+		}
+	}
+
+	if this.config.Timeout == 0 {
+		this.timeout = DefaultTimeout
+	} else {
+		this.timeout = time.Millisecond * this.config.Timeout
+	}
+
+	this.ttl = DefaultTTL
+	if ttl, ok := this.config.Extra["ttl"].(float64); ok && ttl > 0 {
+		this.ttl = int64(ttl)
+	}
+
+	if this.kv == nil {
+		this.kv = NewGatewayKV(this.config.Servers, this.timeout)
+	}
+
+	this.ctx, this.cancel = context.WithCancel(context.Background())
+	return nil
+}
+
+func (this *client) Close() error {
+	if this.cancel != nil {
+		this.cancel()
+	}
+	return nil
+}
+
+func (this *client) nodesPath(name string) string {
+	return this.rootPath + "/" + name + serviceNodesPath
+}
+
+func (this *client) timeoutContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(this.ctx, this.timeout)
+}
+
+// sleep returns false if the client was closed while sleeping
+func (this *client) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-this.ctx.Done():
+		return false
+	}
+}
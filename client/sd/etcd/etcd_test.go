@@ -0,0 +1,210 @@
+package etcd
+
+import (
+	"context"
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/server"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeWatcher struct {
+	ctx    context.Context
+	prefix string
+	ch     chan []*Event
+}
+
+type fakeKV struct {
+	locker   sync.Mutex
+	revision int64
+	leaseId  int64
+	leases   map[int64][]string
+	kvs      map[string]*KeyValue
+	watchers []*fakeWatcher
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{leases: map[int64][]string{}, kvs: map[string]*KeyValue{}}
+}
+
+func (this *fakeKV) notify(events ...*Event) {
+	for _, w := range this.watchers {
+		var matched []*Event
+		for _, e := range events {
+			if strings.HasPrefix(e.Kv.Key, w.prefix) {
+				matched = append(matched, e)
+			}
+		}
+		if len(matched) > 0 && w.ctx.Err() == nil {
+			select {
+			case w.ch <- matched:
+			default:
+			}
+		}
+	}
+}
+
+func (this *fakeKV) Grant(ctx context.Context, ttl int64) (int64, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.leaseId += 1
+	this.leases[this.leaseId] = nil
+	return this.leaseId, nil
+}
+
+func (this *fakeKV) KeepAlive(ctx context.Context, lease int64) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.leases[lease]; !ok {
+		return ErrLeaseNotFound
+	}
+	return nil
+}
+
+func (this *fakeKV) Revoke(ctx context.Context, lease int64) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	var events []*Event
+	for _, key := range this.leases[lease] {
+		if kv := this.kvs[key]; kv != nil {
+			this.revision += 1
+			delete(this.kvs, key)
+			events = append(events, &Event{Type: EventDelete, Kv: &KeyValue{Key: key, ModRevision: this.revision}})
+		}
+	}
+	delete(this.leases, lease)
+	this.notify(events...)
+	return nil
+}
+
+func (this *fakeKV) Create(ctx context.Context, key string, value []byte, lease int64) (bool, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.kvs[key]; ok {
+		return false, nil
+	}
+	this.revision += 1
+	var kv = &KeyValue{Key: key, Value: value, ModRevision: this.revision}
+	this.kvs[key] = kv
+	this.leases[lease] = append(this.leases[lease], key)
+	this.notify(&Event{Type: EventPut, Kv: kv})
+	return true, nil
+}
+
+func (this *fakeKV) Delete(ctx context.Context, key string) error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if _, ok := this.kvs[key]; ok {
+		this.revision += 1
+		delete(this.kvs, key)
+		this.notify(&Event{Type: EventDelete, Kv: &KeyValue{Key: key, ModRevision: this.revision}})
+	}
+	return nil
+}
+
+func (this *fakeKV) List(ctx context.Context, prefix string) ([]*KeyValue, int64, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	var kvs []*KeyValue
+	for key, kv := range this.kvs {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, kv)
+		}
+	}
+	return kvs, this.revision, nil
+}
+
+func (this *fakeKV) Watch(ctx context.Context, prefix string, revision int64) (<-chan []*Event, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	var w = &fakeWatcher{ctx: ctx, prefix: prefix, ch: make(chan []*Event, 16)}
+	this.watchers = append(this.watchers, w)
+	go func() {
+		<-ctx.Done()
+		this.locker.Lock()
+		defer this.locker.Unlock()
+		for i, ow := range this.watchers {
+			if ow == w {
+				this.watchers = append(this.watchers[:i], this.watchers[i+1:]...)
+				break
+			}
+		}
+		close(w.ch)
+	}()
+	return w.ch, nil
+}
+
+func (this *fakeKV) Close() error {
+	return nil
+}
+
+type testServer struct {
+	name  string
+	appId uint32
+}
+
+func (this *testServer) Init() error        { return nil }
+func (this *testServer) Name() string       { return this.name }
+func (this *testServer) AppId() uint32      { return this.appId }
+func (this *testServer) Close() error       { return nil }
+func (this *testServer) Serve() error       { return nil }
+func (this *testServer) Logger() log.Logger { return log.DefaultLogger }
+
+func TestRegisterSubscribe(t *testing.T) {
+	var kv = newFakeKV()
+	var config = &sd.Config{Servers: []string{"fake"}}
+
+	var listener = &server.Listener{}
+	listener.WithNetwork("tcp")
+	listener.WithAddress("127.0.0.1:9999")
+
+	var register = NewRegisterClientWithKV(config, kv)
+	register.SetServer(&testServer{name: "test", appId: 1})
+	register.SetListener(listener, nil)
+	if err := register.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	var updates = make(chan []*sd.ServerNode, 16)
+	var subscriber = NewSubscribeClientWithKV(config, kv)
+	subscriber.AddSubscribe("test", func(service string, nodes []*sd.ServerNode) {
+		updates <- nodes
+	})
+	if err := subscriber.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	go subscriber.Work()
+	defer subscriber.Close()
+
+	var wait = func(n int) {
+		for {
+			select {
+			case nodes := <-updates:
+				if len(nodes) == n {
+					return
+				}
+			case <-time.After(time.Second * 3):
+				t.Fatalf("waiting for %d nodes timeout", n)
+			}
+		}
+	}
+
+	wait(0)
+
+	var done = make(chan struct{})
+	go func() {
+		_ = register.Work()
+		close(done)
+	}()
+
+	wait(1)
+
+	_ = register.Close()
+	<-done
+
+	wait(0)
+}
@@ -0,0 +1,288 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	json "github.com/json-iterator/go"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/util"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var ErrLeaseNotFound = errors.Error("the lease was not found")
+
+type gatewayKV struct {
+	endpoints []string
+	current   atomic.Int32
+
+	client      *http.Client
+	watchClient *http.Client
+}
+
+// NewGatewayKV returns a KV talks to the JSON gRPC gateway(/v3/*) of the etcd servers,
+// the request is sent to the next server when the current one failed.
+func NewGatewayKV(servers []string, timeout time.Duration) KV {
+	var endpoints = make([]string, len(servers))
+	for i, server := range servers {
+		if !strings.Contains(server, "://") {
+			server = "http://" + server
+		}
+		endpoints[i] = strings.TrimSuffix(server, "/")
+	}
+	return &gatewayKV{endpoints: endpoints, client: &http.Client{Timeout: timeout}, watchClient: &http.Client{}}
+}
+
+func encodeKey(key string) string {
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
+
+func decodeKey(key string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(key)
+}
+
+func parseInt(s string) int64 {
+	var n, _ = strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+type gatewayError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (this *gatewayKV) post(ctx context.Context, client *http.Client, path string, req interface{}) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs errors.MultiError
+	for range this.endpoints {
+		var current = int(this.current.Load())
+		hr, err := http.NewRequestWithContext(ctx, http.MethodPost, this.endpoints[current%len(this.endpoints)]+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		hr.Header.Set("Content-Type", "application/json")
+
+		res, err := client.Do(hr)
+		if err == nil {
+			if res.StatusCode == http.StatusOK {
+				return res, nil
+			}
+
+			var ge gatewayError
+			_ = json.NewDecoder(res.Body).Decode(&ge)
+			_ = res.Body.Close()
+			var msg = ge.Message
+			if len(msg) == 0 {
+				msg = ge.Error
+			}
+			if strings.Contains(msg, "requested lease not found") {
+				return nil, ErrLeaseNotFound
+			}
+			return nil, errors.Errorf("etcd gateway responded '%s', %s", res.Status, msg)
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		errs = append(errs, err)
+		this.current.CompareAndSwap(int32(current), int32((current+1)%len(this.endpoints)))
+	}
+	return nil, errs
+}
+
+func (this *gatewayKV) call(ctx context.Context, path string, req, res interface{}) error {
+	hr, err := this.post(ctx, this.client, path, req)
+	if err != nil {
+		return err
+	}
+	defer hr.Body.Close()
+
+	if res == nil {
+		_, _ = io.Copy(io.Discard, hr.Body)
+		return nil
+	}
+	return json.NewDecoder(hr.Body).Decode(res)
+}
+
+func (this *gatewayKV) Grant(ctx context.Context, ttl int64) (int64, error) {
+	var res struct {
+		ID string `json:"ID"`
+	}
+	var err = this.call(ctx, "/v3/lease/grant", map[string]interface{}{"TTL": ttl}, &res)
+	if err != nil {
+		return 0, err
+	}
+	return parseInt(res.ID), nil
+}
+
+func (this *gatewayKV) KeepAlive(ctx context.Context, lease int64) error {
+	var res struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	var err = this.call(ctx, "/v3/lease/keepalive", map[string]interface{}{"ID": lease}, &res)
+	if err != nil {
+		return err
+	}
+	if parseInt(res.Result.TTL) <= 0 {
+		return ErrLeaseNotFound
+	}
+	return nil
+}
+
+func (this *gatewayKV) Revoke(ctx context.Context, lease int64) error {
+	return this.call(ctx, "/v3/lease/revoke", map[string]interface{}{"ID": lease}, nil)
+}
+
+func (this *gatewayKV) Create(ctx context.Context, key string, value []byte, lease int64) (bool, error) {
+	var req = map[string]interface{}{
+		"compare": []map[string]interface{}{{
+			"key":             encodeKey(key),
+			"target":          "CREATE",
+			"result":          "EQUAL",
+			"create_revision": 0,
+		}},
+		"success": []map[string]interface{}{{
+			"request_put": map[string]interface{}{
+				"key":   encodeKey(key),
+				"value": base64.StdEncoding.EncodeToString(value),
+				"lease": lease,
+			},
+		}},
+	}
+
+	var res struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	var err = this.call(ctx, "/v3/kv/txn", req, &res)
+	if err != nil {
+		return false, err
+	}
+	return res.Succeeded, nil
+}
+
+func (this *gatewayKV) Delete(ctx context.Context, key string) error {
+	return this.call(ctx, "/v3/kv/deleterange", map[string]interface{}{"key": encodeKey(key)}, nil)
+}
+
+type gatewayKeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	ModRevision string `json:"mod_revision"`
+}
+
+func (this *gatewayKeyValue) decode() (*KeyValue, error) {
+	key, err := decodeKey(this.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := base64.StdEncoding.DecodeString(this.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyValue{Key: string(key), Value: value, ModRevision: parseInt(this.ModRevision)}, nil
+}
+
+func (this *gatewayKV) List(ctx context.Context, prefix string) ([]*KeyValue, int64, error) {
+	var res struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		Kvs []*gatewayKeyValue `json:"kvs"`
+	}
+
+	var err = this.call(ctx, "/v3/kv/range", map[string]interface{}{"key": encodeKey(prefix), "range_end": encodeKey(prefixEnd(prefix))}, &res)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var kvs = make([]*KeyValue, 0, len(res.Kvs))
+	for _, gkv := range res.Kvs {
+		kv, err := gkv.decode()
+		if err != nil {
+			return nil, 0, err
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, parseInt(res.Header.Revision), nil
+}
+
+func (this *gatewayKV) Watch(ctx context.Context, prefix string, revision int64) (<-chan []*Event, error) {
+	var req = map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            encodeKey(prefix),
+			"range_end":      encodeKey(prefixEnd(prefix)),
+			"start_revision": revision,
+		},
+	}
+
+	hr, err := this.post(ctx, this.watchClient, "/v3/watch", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var ch = make(chan []*Event)
+	go func() {
+		defer close(ch)
+		defer hr.Body.Close()
+
+		var decoder = json.NewDecoder(hr.Body)
+		for {
+			var res struct {
+				Result struct {
+					Canceled bool `json:"canceled"`
+					Events   []struct {
+						Type string           `json:"type"`
+						Kv   *gatewayKeyValue `json:"kv"`
+					} `json:"events"`
+				} `json:"result"`
+			}
+
+			if decoder.Decode(&res) != nil || res.Result.Canceled {
+				return
+			}
+
+			if len(res.Result.Events) == 0 {
+				continue
+			}
+
+			var events = make([]*Event, 0, len(res.Result.Events))
+			for _, ge := range res.Result.Events {
+				if ge.Kv == nil {
+					continue
+				}
+				kv, err := ge.Kv.decode()
+				if err != nil {
+					return
+				}
+				events = append(events, &Event{Type: util.If(ge.Type == "DELETE", EventDelete, EventPut), Kv: kv})
+			}
+
+			select {
+			case ch <- events:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (this *gatewayKV) Close() error {
+	this.client.CloseIdleConnections()
+	this.watchClient.CloseIdleConnections()
+	return nil
+}
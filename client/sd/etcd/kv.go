@@ -0,0 +1,51 @@
+package etcd
+
+import "context"
+
+type EventType int
+
+const (
+	EventPut EventType = iota
+	EventDelete
+)
+
+type KeyValue struct {
+	Key         string
+	Value       []byte
+	ModRevision int64
+}
+
+type Event struct {
+	Type EventType
+	Kv   *KeyValue
+}
+
+// KV is the subset of the etcd v3 API the service register-discovery clients use,
+// NewGatewayKV implements it over the JSON gRPC gateway of etcd, tests can implement it in process.
+type KV interface {
+	// Grant creates a lease with the ttl in seconds.
+	Grant(ctx context.Context, ttl int64) (int64, error)
+	// KeepAlive refreshes the lease once, it returns ErrLeaseNotFound if the lease was expired.
+	KeepAlive(ctx context.Context, lease int64) error
+	Revoke(ctx context.Context, lease int64) error
+	// Create puts the key attached to the lease only if the key does not exist.
+	Create(ctx context.Context, key string, value []byte, lease int64) (bool, error)
+	Delete(ctx context.Context, key string) error
+	// List returns the keys with the prefix and the current revision.
+	List(ctx context.Context, prefix string) ([]*KeyValue, int64, error)
+	// Watch watches the keys with the prefix since the revision,
+	// the channel is closed when ctx is done or the watching failed.
+	Watch(ctx context.Context, prefix string, revision int64) (<-chan []*Event, error)
+	Close() error
+}
+
+func prefixEnd(prefix string) string {
+	var end = []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i] += 1
+			return string(end[:i+1])
+		}
+	}
+	return "\x00"
+}
@@ -0,0 +1,151 @@
+package etcd
+
+import (
+	"context"
+	json "github.com/json-iterator/go"
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/server"
+	"github.com/oylshe1314/framework/util"
+	"strconv"
+	"time"
+)
+
+func NewRegisterClient(config *sd.Config) sd.RegisterClient {
+	return &registerClient{client: client{config: config}}
+}
+
+// NewRegisterClientWithKV returns a register client uses the kv instead of the etcd gateway of config.Servers.
+func NewRegisterClientWithKV(config *sd.Config, kv KV) sd.RegisterClient {
+	return &registerClient{client: client{config: config, kv: kv}}
+}
+
+type registerClient struct {
+	client
+
+	lease   int64
+	svrPath string
+	svrNode *sd.ServerNode
+}
+
+func (this *registerClient) SetListener(inner, exter *server.Listener) {
+	if inner == nil && exter == nil {
+		return
+	}
+	this.svrNode = sd.NewServiceNode(this.server.Name(), this.server.AppId(), inner, exter)
+}
+
+func (this *registerClient) Init() (err error) {
+	if this.server == nil {
+		return errors.Error("Service register-discovery client init 'server' can not be nil")
+	}
+
+	if this.svrNode == nil {
+		return errors.Error("please set service node before init")
+	}
+
+	return this.client.Init()
+}
+
+func (this *registerClient) setServiceNode() error {
+	var node = this.svrNode
+	if len(node.Guid) == 0 {
+		node.Guid = util.UUID()
+	}
+
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := this.timeoutContext()
+	defer cancel()
+
+	lease, err := this.kv.Grant(ctx, this.ttl)
+	if err != nil {
+		return err
+	}
+
+	var svrPath = this.nodesPath(node.Name) + strconv.Itoa(int(node.AppId))
+	created, err := this.kv.Create(ctx, svrPath, data, lease)
+	if err != nil {
+		_ = this.kv.Revoke(ctx, lease)
+		return err
+	}
+
+	if !created {
+		_ = this.kv.Revoke(ctx, lease)
+		return errors.Errorf("service '%s:%d' is already existed", node.Name, node.AppId)
+	}
+
+	this.lease = lease
+	this.svrPath = svrPath
+	return nil
+}
+
+func (this *registerClient) register() bool {
+	for {
+		var err = this.setServiceNode()
+		if err == nil {
+			this.logger.Infof("Service register success, node: %s", this.svrPath)
+			return true
+		}
+
+		this.logger.Error(err)
+		if !this.sleep(time.Second * 3) {
+			return false
+		}
+	}
+}
+
+func (this *registerClient) keepAlive() error {
+	var interval = time.Duration(this.ttl) * time.Second / 3
+	for this.sleep(interval) {
+		ctx, cancel := this.timeoutContext()
+		var err = this.kv.KeepAlive(ctx, this.lease)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *registerClient) deregister() {
+	if len(this.svrPath) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), this.timeout)
+	defer cancel()
+
+	_ = this.kv.Delete(ctx, this.svrPath)
+	_ = this.kv.Revoke(ctx, this.lease)
+	this.logger.Infof("Service deregister success, node: %s", this.svrPath)
+
+	this.lease = 0
+	this.svrPath = ""
+}
+
+func (this *registerClient) Work() error {
+	defer func() {
+		_ = this.kv.Close()
+	}()
+
+	for this.register() {
+		var err = this.keepAlive()
+		if err == nil {
+			break
+		}
+
+		this.logger.Warnf("Service keepalive failed, will register again, node: %s, error: %v", this.svrPath, err)
+		if errors.Is(err, ErrLeaseNotFound) {
+			this.svrPath = ""
+		} else {
+			this.deregister()
+		}
+	}
+
+	this.deregister()
+	return nil
+}
@@ -0,0 +1,137 @@
+package etcd
+
+import (
+	"context"
+	json "github.com/json-iterator/go"
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"sync"
+	"time"
+)
+
+type subItem struct {
+	svrName  string
+	callback sd.SubscribeCallback
+}
+
+func NewSubscribeClient(config *sd.Config) sd.SubscribeClient {
+	return &subscribeClient{client: client{config: config}}
+}
+
+// NewSubscribeClientWithKV returns a subscribe client uses the kv instead of the etcd gateway of config.Servers.
+func NewSubscribeClientWithKV(config *sd.Config, kv KV) sd.SubscribeClient {
+	return &subscribeClient{client: client{config: config, kv: kv}}
+}
+
+type subscribeClient struct {
+	client
+
+	subItems map[string]*subItem
+}
+
+func (this *subscribeClient) AddSubscribe(name string, callback sd.SubscribeCallback) {
+	if this.subItems == nil {
+		this.subItems = make(map[string]*subItem)
+	}
+	this.subItems[name] = &subItem{svrName: name, callback: callback}
+}
+
+func (this *subscribeClient) Init() error {
+	if len(this.subItems) == 0 {
+		return errors.Error("please add subscribe service name before init")
+	}
+
+	return this.client.Init()
+}
+
+func (this *subscribeClient) readServiceData(nodesPath string) ([]*sd.ServerNode, int64, error) {
+	ctx, cancel := this.timeoutContext()
+	defer cancel()
+
+	kvs, revision, err := this.kv.List(ctx, nodesPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var svrNodes []*sd.ServerNode
+	for _, kv := range kvs {
+		if len(kv.Value) == 0 {
+			continue
+		}
+
+		var svrNode = new(sd.ServerNode)
+		err = json.Unmarshal(kv.Value, svrNode)
+		if err != nil {
+			this.logger.Errorf("Unmarshal service node data failed, %v, node: %s, data: %s", err, kv.Key, kv.Value)
+			continue
+		}
+
+		if this.server != nil {
+			if svrNode.Name == this.server.Name() && svrNode.AppId == this.server.AppId() {
+				continue
+			}
+		}
+
+		svrNodes = append(svrNodes, svrNode)
+	}
+	return svrNodes, revision, nil
+}
+
+func (this *subscribeClient) itemLoop(item *subItem) {
+	var nodesPath = this.nodesPath(item.svrName)
+	for this.ctx.Err() == nil {
+		ss, revision, err := this.readServiceData(nodesPath)
+		if err != nil {
+			this.logger.Error(err, ", path: ", nodesPath)
+			if !this.sleep(time.Second * 3) {
+				return
+			}
+			continue
+		}
+
+		item.callback(item.svrName, ss)
+
+		this.watch(item, nodesPath, revision)
+		if !this.sleep(time.Second) {
+			return
+		}
+	}
+}
+
+func (this *subscribeClient) watch(item *subItem, nodesPath string, revision int64) {
+	ctx, cancel := context.WithCancel(this.ctx)
+	defer cancel()
+
+	eventChan, err := this.kv.Watch(ctx, nodesPath, revision+1)
+	if err != nil {
+		this.logger.Error(err, ", path: ", nodesPath)
+		return
+	}
+
+	for range eventChan {
+		ss, _, err := this.readServiceData(nodesPath)
+		if err != nil {
+			this.logger.Error(err, ", path: ", nodesPath)
+			return
+		}
+		item.callback(item.svrName, ss)
+	}
+}
+
+func (this *subscribeClient) Work() error {
+	defer func() {
+		_ = this.kv.Close()
+	}()
+
+	var wg sync.WaitGroup
+	for _, item := range this.subItems {
+		wg.Add(1)
+		go func(item *subItem) {
+			defer wg.Done()
+			this.itemLoop(item)
+		}(item)
+	}
+
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,318 @@
+package sd
+
+import (
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/server"
+	"github.com/oylshe1314/framework/util"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProbeTcp  = "tcp"
+	ProbeHttp = "http"
+)
+
+const (
+	defaultHealthInterval  = time.Millisecond * 5000
+	defaultHealthTimeout   = time.Millisecond * 2000
+	defaultHealthThreshold = 3
+	defaultHealthPath      = "/server/detect"
+)
+
+type nodeHealth struct {
+	node     *ServerNode
+	failures int
+	healthy  bool
+}
+
+type serviceHealth struct {
+	callback SubscribeCallback
+	nodes    []*nodeHealth
+	notified string
+}
+
+// HealthChecker probes the subscribed service nodes periodically, the nodes failed 'threshold' times in a row
+// are filtered out before the wrapped SubscribeCallback runs, and added back once they are probed succeed.
+// The probe is a tcp connecting or a http get of the 'path'(the /server/detect of HttpServer by default).
+type HealthChecker struct {
+	probe     string
+	path      string
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+
+	logger log.Logger
+	server server.Server
+
+	locker       sync.Mutex
+	notifyLocker sync.Mutex
+	services     map[string]*serviceHealth
+
+	httpClient *http.Client
+	closeChan  chan struct{}
+}
+
+func (this *HealthChecker) WithProbe(probe string) {
+	this.probe = probe
+}
+
+func (this *HealthChecker) WithPath(path string) {
+	this.path = path
+}
+
+func (this *HealthChecker) WithInterval(interval int) {
+	this.interval = time.Duration(interval)
+}
+
+func (this *HealthChecker) WithTimeout(timeout int) {
+	this.timeout = time.Duration(timeout)
+}
+
+func (this *HealthChecker) WithThreshold(threshold int) {
+	this.threshold = threshold
+}
+
+func (this *HealthChecker) SetServer(svr server.Server) {
+	this.server = svr
+}
+
+// Wrap returns the callback to subscribe with, it passes the healthy nodes to the callback.
+func (this *HealthChecker) Wrap(callback SubscribeCallback) SubscribeCallback {
+	var health = &serviceHealth{callback: callback}
+	return func(service string, nodes []*ServerNode) {
+		this.locker.Lock()
+		if this.services == nil {
+			this.services = map[string]*serviceHealth{}
+		}
+		this.services[service] = health
+
+		var oldNodes = map[uint32]*nodeHealth{}
+		for _, nh := range health.nodes {
+			oldNodes[nh.node.AppId] = nh
+		}
+
+		health.nodes = make([]*nodeHealth, 0, len(nodes))
+		for _, node := range nodes {
+			var nh = oldNodes[node.AppId]
+			if nh != nil && nh.node.Guid == node.Guid && probeAddress(nh.node) == probeAddress(node) {
+				nh.node = node
+			} else {
+				nh = &nodeHealth{node: node, healthy: true}
+			}
+			health.nodes = append(health.nodes, nh)
+		}
+		this.locker.Unlock()
+
+		this.notify(service, health, true)
+	}
+}
+
+func (this *HealthChecker) notify(service string, health *serviceHealth, force bool) {
+	this.notifyLocker.Lock()
+	defer this.notifyLocker.Unlock()
+
+	this.locker.Lock()
+	var healthy []*ServerNode
+	var signature strings.Builder
+	for _, nh := range health.nodes {
+		if nh.healthy {
+			healthy = append(healthy, nh.node)
+			// the Guid of the static nodes is empty
+			signature.WriteString(util.IntegerToString(nh.node.AppId))
+			signature.WriteByte('@')
+			signature.WriteString(probeAddress(nh.node))
+			signature.WriteByte(',')
+		}
+	}
+
+	if !force && health.notified == signature.String() {
+		this.locker.Unlock()
+		return
+	}
+	health.notified = signature.String()
+	this.locker.Unlock()
+
+	health.callback(service, healthy)
+}
+
+func (this *HealthChecker) Init() error {
+	if this.server == nil {
+		this.logger = log.DefaultLogger
+	} else {
+		this.logger = this.server.Logger()
+	}
+
+	if len(this.probe) == 0 {
+		this.probe = ProbeTcp
+	}
+
+	switch this.probe {
+	case ProbeTcp, ProbeHttp:
+	default:
+		return errors.Errorf("unknown health probe '%s'", this.probe)
+	}
+
+	if len(this.path) == 0 {
+		this.path = defaultHealthPath
+	}
+
+	if this.interval <= 0 {
+		this.interval = defaultHealthInterval
+	} else {
+		this.interval = this.interval * time.Millisecond
+	}
+
+	if this.timeout <= 0 {
+		this.timeout = defaultHealthTimeout
+	} else {
+		this.timeout = this.timeout * time.Millisecond
+	}
+
+	if this.threshold <= 0 {
+		this.threshold = defaultHealthThreshold
+	}
+
+	this.httpClient = &http.Client{Timeout: this.timeout}
+	this.closeChan = make(chan struct{})
+	return nil
+}
+
+func (this *HealthChecker) Close() error {
+	if this.closeChan != nil {
+		select {
+		case <-this.closeChan:
+		default:
+			close(this.closeChan)
+		}
+	}
+	return nil
+}
+
+func (this *HealthChecker) Work() error {
+	for {
+		select {
+		case <-this.closeChan:
+			this.httpClient.CloseIdleConnections()
+			return nil
+		case <-time.After(this.interval):
+		}
+
+		this.check()
+	}
+}
+
+func (this *HealthChecker) check() {
+	this.locker.Lock()
+	var services = map[string]*serviceHealth{}
+	var fs []func() error
+	for service, health := range this.services {
+		services[service] = health
+		for _, nh := range health.nodes {
+			var node = nh.node
+			var result = nh
+			fs = append(fs, func() error {
+				var err = this.probeNode(node)
+				this.update(service, result, err)
+				return err
+			})
+		}
+	}
+	this.locker.Unlock()
+
+	util.WaitAll(fs...)
+
+	for service, health := range services {
+		this.notify(service, health, false)
+	}
+}
+
+func (this *HealthChecker) update(service string, nh *nodeHealth, err error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if err == nil {
+		if !nh.healthy {
+			this.logger.Infof("The service node recovered, service: %s, appId: %d", service, nh.node.AppId)
+		}
+		nh.failures = 0
+		nh.healthy = true
+		return
+	}
+
+	nh.failures += 1
+	if nh.healthy && nh.failures >= this.threshold {
+		nh.healthy = false
+		this.logger.Warnf("The service node is unhealthy, service: %s, appId: %d, error: %v", service, nh.node.AppId, err)
+	}
+}
+
+func probeNetwork(node *ServerNode) *ServerNetwork {
+	if node.Inner != nil {
+		return node.Inner
+	}
+	return node.Exter
+}
+
+func probeAddress(node *ServerNode) string {
+	var sn = probeNetwork(node)
+	if sn == nil {
+		return ""
+	}
+	return sn.Network + "/" + sn.Address
+}
+
+func (this *HealthChecker) probeNode(node *ServerNode) error {
+	var sn = probeNetwork(node)
+	if sn == nil {
+		return errors.Error("the service node has no network information")
+	}
+
+	var address = sn.Address
+	var hasScheme = strings.Contains(address, "://")
+
+	switch this.probe {
+	case ProbeHttp:
+		if !hasScheme {
+			address = "http://" + address
+		}
+
+		res, err := this.httpClient.Get(strings.TrimSuffix(address, "/") + this.path)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return errors.Errorf("health probe responded '%s'", res.Status)
+		}
+		return nil
+	default:
+		if hasScheme {
+			u, err := url.Parse(address)
+			if err != nil {
+				return err
+			}
+			address = u.Host
+			if len(u.Port()) == 0 {
+				address = net.JoinHostPort(u.Hostname(), util.If(u.Scheme == "https" || u.Scheme == "wss", "443", "80"))
+			}
+		}
+
+		var network = sn.Network
+		if len(network) == 0 {
+			network = "tcp"
+		}
+
+		conn, err := net.DialTimeout(network, address, this.timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
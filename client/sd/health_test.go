@@ -0,0 +1,67 @@
+package sd
+
+import (
+	"net"
+	"testing"
+)
+
+func TestHealthChecker(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = closed.Close()
+
+	var checker = &HealthChecker{}
+	checker.WithThreshold(2)
+	if err = checker.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	var notified [][]*ServerNode
+	var callback = checker.Wrap(func(service string, nodes []*ServerNode) {
+		notified = append(notified, nodes)
+	})
+
+	// the static nodes have no Guid
+	var nodes = []*ServerNode{
+		{Name: "test", AppId: 1, Inner: &ServerNetwork{Network: "tcp", Address: l.Addr().String()}},
+		{Name: "test", AppId: 2, Inner: &ServerNetwork{Network: "tcp", Address: closed.Addr().String()}},
+	}
+	callback("test", nodes)
+	if len(notified) != 1 || len(notified[0]) != 2 {
+		t.Fatal("the wrapped callback should be called with all the nodes")
+	}
+
+	checker.check()
+	if len(notified) != 1 {
+		t.Fatal("the node should not be filtered out before the threshold")
+	}
+
+	checker.check()
+	if len(notified) != 2 || len(notified[1]) != 1 || notified[1][0].AppId != 1 {
+		t.Fatal("the failed node should be filtered out at the threshold")
+	}
+
+	// node 1 failed and node 2 recovered in the same round, the count of the healthy nodes is unchanged
+	var health = checker.services["test"]
+	for range 2 {
+		checker.update("test", health.nodes[0], net.ErrClosed)
+	}
+	checker.update("test", health.nodes[1], nil)
+	checker.notify("test", health, false)
+	if len(notified) != 3 || len(notified[2]) != 1 || notified[2][0].AppId != 2 {
+		t.Fatal("the recovered node should be notified")
+	}
+
+	checker.notify("test", health, false)
+	if len(notified) != 3 {
+		t.Fatal("the callback should not be called without changes")
+	}
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/server"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	name  string
+	appId uint32
+}
+
+func (this *testServer) Init() error        { return nil }
+func (this *testServer) Name() string       { return this.name }
+func (this *testServer) AppId() uint32      { return this.appId }
+func (this *testServer) Close() error       { return nil }
+func (this *testServer) Serve() error       { return nil }
+func (this *testServer) Logger() log.Logger { return log.DefaultLogger }
+
+func newTestRegisterClient(t *testing.T, registry *Registry, appId uint32) sd.RegisterClient {
+	var listener = &server.Listener{}
+	listener.WithNetwork("tcp")
+	listener.WithAddress("127.0.0.1:9999")
+
+	var client = NewRegisterClient(registry)
+	client.SetServer(&testServer{name: "test", appId: appId})
+	client.SetListener(listener, nil)
+	if err := client.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRegistry(t *testing.T) {
+	var registry = NewRegistry()
+
+	var counts []int
+	var subscriber = NewSubscribeClient(registry)
+	subscriber.SetServer(&testServer{name: "test", appId: 3})
+	subscriber.AddSubscribe("test", func(service string, nodes []*sd.ServerNode) {
+		counts = append(counts, len(nodes))
+	})
+	if err := subscriber.Init(); err != nil {
+		t.Fatal(err)
+	}
+	go subscriber.Work()
+	defer subscriber.Close()
+
+	var r1 = newTestRegisterClient(t, registry, 1)
+	var r2 = newTestRegisterClient(t, registry, 2)
+	var r3 = newTestRegisterClient(t, registry, 3)
+	go r1.Work()
+	go r2.Work()
+	go r3.Work()
+
+	for len(registry.Nodes("test")) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := newTestRegisterClient(t, registry, 1).Work(); err == nil {
+		t.Fatal("the duplicate node was registered")
+	}
+
+	_ = r1.Close()
+	_ = r2.Close()
+	_ = r3.Close()
+
+	if len(registry.Nodes("test")) != 0 {
+		t.Fatal("the nodes were not deregistered")
+	}
+
+	var last = counts[len(counts)-1]
+	if last != 0 {
+		t.Fatalf("the last callback received %d nodes", last)
+	}
+
+	for _, count := range counts {
+		if count > 2 {
+			t.Fatal("the subscriber received itself")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/server"
+	"sync"
+)
+
+// NewRegisterClient returns a register client of the registry, nil means DefaultRegistry.
+func NewRegisterClient(registry *Registry) sd.RegisterClient {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	return &registerClient{registry: registry}
+}
+
+type registerClient struct {
+	registry *Registry
+	server   server.Server
+
+	svrNode *sd.ServerNode
+
+	locker     sync.Mutex
+	registered bool
+	closeChan  chan struct{}
+}
+
+func (this *registerClient) SetServer(svr server.Server) {
+	this.server = svr
+}
+
+func (this *registerClient) SetListener(inner, exter *server.Listener) {
+	if inner == nil && exter == nil {
+		return
+	}
+	this.svrNode = sd.NewServiceNode(this.server.Name(), this.server.AppId(), inner, exter)
+}
+
+func (this *registerClient) Init() error {
+	if this.server == nil {
+		return errors.Error("Service register-discovery client init 'server' can not be nil")
+	}
+
+	if this.svrNode == nil {
+		return errors.Error("please set service node before init")
+	}
+
+	this.closeChan = make(chan struct{})
+	return nil
+}
+
+func (this *registerClient) Close() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.registered {
+		this.registered = false
+		this.registry.deregister(this.svrNode)
+		this.server.Logger().Infof("Service deregister success, node: %s:%d", this.svrNode.Name, this.svrNode.AppId)
+	}
+
+	if this.closeChan != nil {
+		select {
+		case <-this.closeChan:
+		default:
+			close(this.closeChan)
+		}
+	}
+	return nil
+}
+
+func (this *registerClient) Work() error {
+	this.locker.Lock()
+	select {
+	case <-this.closeChan:
+		this.locker.Unlock()
+		return nil
+	default:
+	}
+
+	var err = this.registry.register(this.svrNode)
+	if err != nil {
+		this.locker.Unlock()
+		return err
+	}
+	this.registered = true
+	this.locker.Unlock()
+
+	this.server.Logger().Infof("Service register success, node: %s:%d", this.svrNode.Name, this.svrNode.AppId)
+
+	<-this.closeChan
+	return nil
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/util"
+	"sort"
+	"sync"
+)
+
+type subscription struct {
+	name     string
+	filter   func(node *sd.ServerNode) bool
+	callback sd.SubscribeCallback
+}
+
+// Registry keeps the service nodes in process, the register and subscribe clients created
+// with the same registry see each other, the changes are notified to the subscribers synchronously.
+type Registry struct {
+	locker       sync.Mutex
+	notifyLocker sync.Mutex
+
+	nodes         map[string]map[uint32]*sd.ServerNode
+	subscriptions map[string][]*subscription
+}
+
+func NewRegistry() *Registry {
+	return &Registry{nodes: map[string]map[uint32]*sd.ServerNode{}, subscriptions: map[string][]*subscription{}}
+}
+
+// DefaultRegistry is shared by the clients created without a registry.
+var DefaultRegistry = NewRegistry()
+
+func (this *Registry) snapshot(name string) []*sd.ServerNode {
+	var nodes = util.MapValues(this.nodes[name])
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].AppId < nodes[j].AppId })
+	return nodes
+}
+
+func (this *Registry) notify(sub *subscription, nodes []*sd.ServerNode) {
+	var svrNodes []*sd.ServerNode
+	for _, node := range nodes {
+		if sub.filter == nil || sub.filter(node) {
+			svrNodes = append(svrNodes, node)
+		}
+	}
+	sub.callback(sub.name, svrNodes)
+}
+
+func (this *Registry) change(name string, modify func() error) error {
+	this.notifyLocker.Lock()
+	defer this.notifyLocker.Unlock()
+
+	this.locker.Lock()
+	var err = modify()
+	if err != nil {
+		this.locker.Unlock()
+		return err
+	}
+
+	var nodes = this.snapshot(name)
+	var subs = append([]*subscription{}, this.subscriptions[name]...)
+	this.locker.Unlock()
+
+	for _, sub := range subs {
+		this.notify(sub, nodes)
+	}
+	return nil
+}
+
+func (this *Registry) register(node *sd.ServerNode) error {
+	return this.change(node.Name, func() error {
+		var nodes = this.nodes[node.Name]
+		if nodes == nil {
+			nodes = map[uint32]*sd.ServerNode{}
+			this.nodes[node.Name] = nodes
+		}
+
+		if nodes[node.AppId] != nil {
+			return errors.Errorf("service '%s:%d' is already existed", node.Name, node.AppId)
+		}
+
+		nodes[node.AppId] = node
+		return nil
+	})
+}
+
+func (this *Registry) deregister(node *sd.ServerNode) {
+	_ = this.change(node.Name, func() error {
+		var nodes = this.nodes[node.Name]
+		if nodes[node.AppId] == node {
+			delete(nodes, node.AppId)
+		}
+		if len(nodes) == 0 {
+			delete(this.nodes, node.Name)
+		}
+		return nil
+	})
+}
+
+func (this *Registry) subscribe(sub *subscription) {
+	this.notifyLocker.Lock()
+	defer this.notifyLocker.Unlock()
+
+	this.locker.Lock()
+	this.subscriptions[sub.name] = append(this.subscriptions[sub.name], sub)
+	var nodes = this.snapshot(sub.name)
+	this.locker.Unlock()
+
+	this.notify(sub, nodes)
+}
+
+func (this *Registry) unsubscribe(sub *subscription) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var subs = this.subscriptions[sub.name]
+	for i, s := range subs {
+		if s == sub {
+			this.subscriptions[sub.name] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(this.subscriptions[sub.name]) == 0 {
+		delete(this.subscriptions, sub.name)
+	}
+}
+
+// Nodes returns the registered nodes of the service.
+func (this *Registry) Nodes(name string) []*sd.ServerNode {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.snapshot(name)
+}
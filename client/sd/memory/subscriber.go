@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/server"
+)
+
+// NewSubscribeClient returns a subscribe client of the registry, nil means DefaultRegistry.
+func NewSubscribeClient(registry *Registry) sd.SubscribeClient {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	return &subscribeClient{registry: registry}
+}
+
+type subscribeClient struct {
+	registry *Registry
+	server   server.Server
+
+	subItems map[string]*subscription
+
+	closeChan chan struct{}
+}
+
+func (this *subscribeClient) SetServer(svr server.Server) {
+	this.server = svr
+}
+
+func (this *subscribeClient) AddSubscribe(name string, callback sd.SubscribeCallback) {
+	if this.subItems == nil {
+		this.subItems = make(map[string]*subscription)
+	}
+	this.subItems[name] = &subscription{name: name, callback: callback}
+}
+
+func (this *subscribeClient) Init() error {
+	if len(this.subItems) == 0 {
+		return errors.Error("please add subscribe service name before init")
+	}
+
+	if this.server != nil {
+		for _, sub := range this.subItems {
+			sub.filter = func(node *sd.ServerNode) bool {
+				return node.Name != this.server.Name() || node.AppId != this.server.AppId()
+			}
+		}
+	}
+
+	this.closeChan = make(chan struct{})
+	return nil
+}
+
+func (this *subscribeClient) Close() error {
+	if this.closeChan != nil {
+		select {
+		case <-this.closeChan:
+		default:
+			close(this.closeChan)
+		}
+	}
+	return nil
+}
+
+func (this *subscribeClient) Work() error {
+	for _, sub := range this.subItems {
+		this.registry.subscribe(sub)
+	}
+
+	<-this.closeChan
+
+	for _, sub := range this.subItems {
+		this.registry.unsubscribe(sub)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package static
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"time"
+)
+
+const DefaultInterval = time.Millisecond * 1000
+
+// Config holds the service nodes declared in the config, or the file(a json array of sd.ServerNode)
+// that is watched for changes every interval milliseconds. The nodes of the file take precedence.
+type Config struct {
+	Nodes    []*sd.ServerNode
+	File     string
+	Interval time.Duration
+}
+
+func (this *Config) WithNodes(nodes []*sd.ServerNode) {
+	this.Nodes = nodes
+}
+
+func (this *Config) WithFile(file string) {
+	this.File = file
+}
+
+func (this *Config) WithInterval(interval int) {
+	this.Interval = time.Duration(interval)
+}
+
+func (this *Config) Init() error {
+	if len(this.Nodes) == 0 && len(this.File) == 0 {
+		return errors.Error("at least one of 'nodes' and 'file' is not empty")
+	}
+
+	for _, node := range this.Nodes {
+		if node == nil || len(node.Name) == 0 {
+			return errors.Error("the name of the static service node cannot be empty")
+		}
+	}
+	return nil
+}
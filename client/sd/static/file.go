@@ -0,0 +1,97 @@
+package static
+
+import (
+	json "github.com/json-iterator/go"
+	"github.com/oylshe1314/framework/client/sd"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type fileStat struct {
+	size    int64
+	modTime time.Time
+}
+
+func statFile(file string) (*fileStat, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	return &fileStat{size: fi.Size(), modTime: fi.ModTime()}, nil
+}
+
+func (this *fileStat) changed(other *fileStat) bool {
+	return other == nil || this.size != other.size || !this.modTime.Equal(other.modTime)
+}
+
+func readNodes(file string) ([]*sd.ServerNode, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var nodes []*sd.ServerNode
+	if len(buf) == 0 {
+		return nil, nil
+	}
+
+	err = json.Unmarshal(buf, &nodes)
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// mergeNodes appends the nodes of the config which are not in the file, the nodes of the file take precedence.
+func mergeNodes(fileNodes, nodes []*sd.ServerNode) []*sd.ServerNode {
+	var exists = map[string]bool{}
+	var merged = make([]*sd.ServerNode, 0, len(fileNodes)+len(nodes))
+	for _, node := range fileNodes {
+		if node != nil {
+			exists[nodeKey(node)] = true
+			merged = append(merged, node)
+		}
+	}
+	for _, node := range nodes {
+		if node != nil && !exists[nodeKey(node)] {
+			merged = append(merged, node)
+		}
+	}
+	return merged
+}
+
+func nodeKey(node *sd.ServerNode) string {
+	return node.Name + ":" + strconv.FormatUint(uint64(node.AppId), 10)
+}
+
+func writeNodes(file string, nodes []*sd.ServerNode) error {
+	buf, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(buf)
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package static
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile locks the file.lock exclusively, the processes updating the file are serialized by it.
+func lockFile(file string) (func(), error) {
+	f, err := os.OpenFile(file+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
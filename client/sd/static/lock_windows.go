@@ -0,0 +1,28 @@
+//go:build windows
+
+package static
+
+import (
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+// lockFile locks the file.lock exclusively, the processes updating the file are serialized by it.
+func lockFile(file string) (func(), error) {
+	f, err := os.OpenFile(file+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	var ol = new(windows.Overlapped)
+	err = windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, ol)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return func() {
+		_ = windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, ol)
+		_ = f.Close()
+	}, nil
+}
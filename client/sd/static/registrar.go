@@ -0,0 +1,123 @@
+package static
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/server"
+)
+
+func NewRegisterClient(config *Config) sd.RegisterClient {
+	return &registerClient{config: config}
+}
+
+// registerClient writes the service node into the file and removes it while closing,
+// it only waits for closing if the file is not set, the static nodes are declared in the config.
+type registerClient struct {
+	config *Config
+
+	logger log.Logger
+	server server.Server
+
+	svrNode *sd.ServerNode
+
+	closeChan chan struct{}
+}
+
+func (this *registerClient) SetServer(svr server.Server) {
+	this.server = svr
+}
+
+func (this *registerClient) SetListener(inner, exter *server.Listener) {
+	if inner == nil && exter == nil {
+		return
+	}
+	this.svrNode = sd.NewServiceNode(this.server.Name(), this.server.AppId(), inner, exter)
+}
+
+func (this *registerClient) Init() error {
+	if this.config == nil {
+		return errors.Error("Service register-discovery client init 'config' can not be nil")
+	}
+
+	if this.server == nil {
+		return errors.Error("Service register-discovery client init 'server' can not be nil")
+	}
+
+	if this.svrNode == nil {
+		return errors.Error("please set service node before init")
+	}
+
+	this.logger = this.server.Logger()
+	this.closeChan = make(chan struct{})
+	return nil
+}
+
+func (this *registerClient) Close() error {
+	if this.closeChan != nil {
+		select {
+		case <-this.closeChan:
+		default:
+			close(this.closeChan)
+		}
+	}
+	return nil
+}
+
+// updateFile adds or removes the node in the file, the file is shared by the servers, so it is locked while
+// reading and rewriting.
+func (this *registerClient) updateFile(register bool) error {
+	unlock, err := lockFile(this.config.File)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	nodes, err := readNodes(this.config.File)
+	if err != nil {
+		return err
+	}
+
+	var newNodes = make([]*sd.ServerNode, 0, len(nodes)+1)
+	for _, node := range nodes {
+		if node.Name == this.svrNode.Name && node.AppId == this.svrNode.AppId {
+			if register && node.Guid != this.svrNode.Guid {
+				this.logger.Warnf("The service node in the file will be replaced, service: %s, appId: %d", node.Name, node.AppId)
+			}
+			continue
+		}
+		newNodes = append(newNodes, node)
+	}
+
+	if register {
+		newNodes = append(newNodes, this.svrNode)
+	}
+
+	return writeNodes(this.config.File, newNodes)
+}
+
+func (this *registerClient) Work() error {
+	if len(this.config.File) == 0 {
+		this.logger.Info("Service register is static, node: ", this.svrNode.Name, ":", this.svrNode.AppId)
+		<-this.closeChan
+		return nil
+	}
+
+	var err = this.updateFile(true)
+	if err != nil {
+		return err
+	}
+
+	this.logger.Infof("Service register success, file: %s, node: %s:%d", this.config.File, this.svrNode.Name, this.svrNode.AppId)
+
+	<-this.closeChan
+
+	err = this.updateFile(false)
+	if err != nil {
+		this.logger.Errorf("Service deregister failed, file: %s, error: %v", this.config.File, err)
+		return err
+	}
+
+	this.logger.Infof("Service deregister success, file: %s, node: %s:%d", this.config.File, this.svrNode.Name, this.svrNode.AppId)
+	return nil
+}
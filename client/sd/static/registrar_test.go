@@ -0,0 +1,33 @@
+package static
+
+import (
+	"github.com/oylshe1314/framework/log"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestRegisterConcurrently(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "nodes.json")
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		var client = &registerClient{config: &Config{File: file}, logger: log.DefaultLogger, svrNode: testNode(uint32(i), "")}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.updateFile(true); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	nodes, err := readNodes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 20 {
+		t.Fatalf("%d nodes were registered, expected 20", len(nodes))
+	}
+}
@@ -0,0 +1,139 @@
+package static
+
+import (
+	json "github.com/json-iterator/go"
+	"github.com/oylshe1314/framework/client/sd"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/server"
+	"time"
+)
+
+func NewSubscribeClient(config *Config) sd.SubscribeClient {
+	return &subscribeClient{config: config}
+}
+
+type subscribeClient struct {
+	config *Config
+
+	logger log.Logger
+	server server.Server
+
+	subItems map[string]sd.SubscribeCallback
+	current  map[string]string
+
+	closeChan chan struct{}
+}
+
+func (this *subscribeClient) SetServer(svr server.Server) {
+	this.server = svr
+}
+
+func (this *subscribeClient) AddSubscribe(name string, callback sd.SubscribeCallback) {
+	if this.subItems == nil {
+		this.subItems = make(map[string]sd.SubscribeCallback)
+	}
+	this.subItems[name] = callback
+}
+
+func (this *subscribeClient) Init() error {
+	if this.config == nil {
+		return errors.Error("Service register-discovery client init 'config' can not be nil")
+	}
+
+	if len(this.subItems) == 0 {
+		return errors.Error("please add subscribe service name before init")
+	}
+
+	if this.server == nil {
+		this.logger = log.DefaultLogger
+	} else {
+		this.logger = this.server.Logger()
+	}
+
+	this.current = map[string]string{}
+	this.closeChan = make(chan struct{})
+	return nil
+}
+
+func (this *subscribeClient) Close() error {
+	if this.closeChan != nil {
+		select {
+		case <-this.closeChan:
+		default:
+			close(this.closeChan)
+		}
+	}
+	return nil
+}
+
+func (this *subscribeClient) dispatch(nodes []*sd.ServerNode) {
+	var services = map[string][]*sd.ServerNode{}
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+
+		if this.server != nil {
+			if node.Name == this.server.Name() && node.AppId == this.server.AppId() {
+				continue
+			}
+		}
+
+		services[node.Name] = append(services[node.Name], node)
+	}
+
+	for name, callback := range this.subItems {
+		var svrNodes = services[name]
+
+		data, _ := json.Marshal(svrNodes)
+		if last, ok := this.current[name]; ok && last == string(data) {
+			continue
+		}
+		this.current[name] = string(data)
+
+		callback(name, svrNodes)
+	}
+}
+
+func (this *subscribeClient) Work() error {
+	if len(this.config.File) == 0 {
+		this.dispatch(this.config.Nodes)
+		<-this.closeChan
+		return nil
+	}
+
+	var interval = DefaultInterval
+	if this.config.Interval > 0 {
+		interval = time.Millisecond * this.config.Interval
+	}
+
+	var last *fileStat
+	var failed bool
+	for {
+		stat, err := statFile(this.config.File)
+		if err != nil {
+			if !failed {
+				failed = true
+				this.logger.Warnf("Stat the service nodes file failed, file: %s, error: %v", this.config.File, err)
+			}
+			last = nil
+			this.dispatch(this.config.Nodes)
+		} else if stat.changed(last) {
+			failed = false
+			nodes, err := readNodes(this.config.File)
+			if err != nil {
+				this.logger.Errorf("Read the service nodes file failed, file: %s, error: %v", this.config.File, err)
+			} else {
+				last = stat
+				this.dispatch(mergeNodes(nodes, this.config.Nodes))
+			}
+		}
+
+		select {
+		case <-this.closeChan:
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package static
+
+import (
+	"github.com/oylshe1314/framework/client/sd"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testNode(appId uint32, address string) *sd.ServerNode {
+	return &sd.ServerNode{Name: "test", AppId: appId, Inner: &sd.ServerNetwork{Network: "tcp", Address: address}}
+}
+
+func TestSubscribeFile(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "nodes.json")
+	if err := writeNodes(file, []*sd.ServerNode{testNode(1, "file:1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var config = &Config{Nodes: []*sd.ServerNode{testNode(1, "config:1"), testNode(2, "config:2")}, File: file, Interval: 10}
+	var notified = make(chan []*sd.ServerNode, 4)
+	var client = NewSubscribeClient(config)
+	client.AddSubscribe("test", func(service string, nodes []*sd.ServerNode) {
+		notified <- nodes
+	})
+	if err := client.Init(); err != nil {
+		t.Fatal(err)
+	}
+	go client.Work()
+	defer client.Close()
+
+	var expect = func(addresses ...string) {
+		select {
+		case nodes := <-notified:
+			if len(nodes) != len(addresses) {
+				t.Fatalf("%d nodes, expected %v", len(nodes), addresses)
+			}
+			for i, node := range nodes {
+				if node.Inner.Address != addresses[i] {
+					t.Fatalf("node %d address %s, expected %s", node.AppId, node.Inner.Address, addresses[i])
+				}
+			}
+		case <-time.After(time.Second * 3):
+			t.Fatal("timeout")
+		}
+	}
+
+	expect("file:1", "config:2")
+
+	if err := writeNodes(file, []*sd.ServerNode{testNode(1, "file:1"), testNode(3, "file:3")}); err != nil {
+		t.Fatal(err)
+	}
+	expect("file:1", "file:3", "config:2")
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/oylshe1314/framework/client/sd"
 	"github.com/oylshe1314/framework/client/sd/zk"
 	"github.com/oylshe1314/framework/server"
@@ -44,6 +45,10 @@ func (this *testNetServer) Serve() error {
 	return util.WaitAny(this.NetServer.Serve, this.RegisterClient.Work)
 }
 
+func (this *testNetServer) Shutdown(ctx context.Context) error {
+	return this.NetServer.Shutdown(ctx)
+}
+
 func (this *testNetServer) Close() error {
 	_ = this.RegisterClient.Close()
 	_ = this.NetServer.Close()
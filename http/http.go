@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	json "github.com/json-iterator/go"
 	"github.com/oylshe1314/framework/errors"
 	"github.com/oylshe1314/framework/message"
@@ -17,6 +18,12 @@ type Message struct {
 	W http.ResponseWriter
 }
 
+// Context returns the context of the request, it carries the trace span of the handler, and it is cancelled
+// when the request ends, the client goes away or the timeout of the handler is reached.
+func (this *Message) Context() context.Context {
+	return this.R.Context()
+}
+
 func (this *Message) Read(v interface{}) error {
 	if v == nil {
 		return nil
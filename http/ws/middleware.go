@@ -0,0 +1,125 @@
+package ws
+
+import (
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/trace"
+	"github.com/oylshe1314/framework/util"
+	"runtime/debug"
+	"time"
+)
+
+// Middleware wraps a MessageHandler to run the cross-cutting logic before or after it.
+type Middleware func(MessageHandler) MessageHandler
+
+// WsUse adds the middlewares to all the messages, including the ones of the default handler,
+// the first added one runs first. The middlewares must be added before serving the connections.
+func (this *ConnMux) WsUse(middlewares ...Middleware) {
+	this.middlewares = append(this.middlewares, middlewares...)
+	this.rechain()
+}
+
+// WsUseModule adds the middlewares to the messages of the module, they run after the ones added by WsUse.
+func (this *ConnMux) WsUseModule(modId uint16, middlewares ...Middleware) {
+	if this.moduleMiddlewares == nil {
+		this.moduleMiddlewares = make(map[uint16][]Middleware)
+	}
+	this.moduleMiddlewares[modId] = append(this.moduleMiddlewares[modId], middlewares...)
+	this.rechain()
+}
+
+func wrap(handler MessageHandler, middlewares []Middleware) MessageHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+func (this *ConnMux) chain(modId uint16, handler MessageHandler) MessageHandler {
+	return wrap(wrap(handler, this.moduleMiddlewares[modId]), this.middlewares)
+}
+
+// rechain composes the middlewares with the handlers when either of them is changed, so that the chains
+// are not composed on every message. The default handler has a chain for each module with the middlewares.
+func (this *ConnMux) rechain() {
+	for id, route := range this.messageHandlers {
+		modId, _ := util.Split2uint16(id)
+		route.chained = this.chain(modId, route.handler)
+	}
+
+	this.defaultChained = nil
+	this.defaultChains = nil
+	if this.defaultHandler == nil {
+		return
+	}
+
+	this.defaultChained = wrap(this.defaultHandler, this.middlewares)
+	for modId := range this.moduleMiddlewares {
+		if this.defaultChains == nil {
+			this.defaultChains = make(map[uint16]MessageHandler)
+		}
+		this.defaultChains[modId] = this.chain(modId, this.defaultHandler)
+	}
+}
+
+func (this *ConnMux) defaultChain(modId uint16) MessageHandler {
+	var handler = this.defaultChains[modId]
+	if handler != nil {
+		return handler
+	}
+	return this.defaultChained
+}
+
+// Recover recovers the panic of the handler and logs it, so that the connection keeps serving the next messages.
+func Recover() Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return func(msg *Message) {
+			defer func() {
+				var err = recover()
+				if err != nil {
+					var logger = msg.Conn.logger.WithFields(msg.logFields())
+					logger.Error("Handle message panic, ", err)
+					logger.Error(string(debug.Stack()))
+					if span := trace.SpanFromContext(msg.Context()); span != nil {
+						span.SetError(errors.Errorf("panic: %v", err))
+					}
+				}
+			}()
+			next(msg)
+		}
+	}
+}
+
+// Timing logs the duration of the handler at the debug level, or at the warn level if it is longer than slow.
+func Timing(slow time.Duration) Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return func(msg *Message) {
+			var begin = time.Now()
+			next(msg)
+			var elapsed = time.Since(begin)
+			if slow > 0 && elapsed >= slow {
+				msg.Conn.logger.WithFields(msg.logFields()).Warn("Handle message slowly, elapsed: ", elapsed)
+			} else if msg.Conn.logger.IsDebugEnabled() && !msg.Conn.isHeartbeat(msg.ModId, msg.MsgId) {
+				msg.Conn.logger.WithFields(msg.logFields()).Debug("Handle message, elapsed: ", elapsed)
+			}
+		}
+	}
+}
+
+// AuthRequired drops the message if the connection has no bound object, the denied handler is called
+// instead if it is not nil, e.g. to reply an error code.
+func AuthRequired(denied MessageHandler) Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return func(msg *Message) {
+			if msg.Conn.Object() != nil {
+				next(msg)
+				return
+			}
+
+			if denied != nil {
+				denied(msg)
+			} else {
+				msg.Conn.logger.WithFields(msg.logFields()).Warn("The message requires the authentication")
+			}
+		}
+	}
+}
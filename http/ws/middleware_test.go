@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/oylshe1314/framework/log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var trail = make(chan string, 8)
+	var mark = func(name string) Middleware {
+		return func(next MessageHandler) MessageHandler {
+			return func(msg *Message) {
+				trail <- name
+				next(msg)
+			}
+		}
+	}
+
+	var serverMux = &ConnMux{}
+	serverMux.WsMessageHandler(1, 1, func(msg *Message) { panic("boom") })
+	serverMux.WsDefaultHandler(func(msg *Message) { trail <- "default" })
+	serverMux.WsUse(Recover(), mark("global"))
+	serverMux.WsUseModule(2, mark("module"))
+
+	var upgrader websocket.Upgrader
+	var svr = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wc, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = NewConn(wc, log.DefaultLogger, serverMux).Serve()
+	}))
+	defer svr.Close()
+
+	wc, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(svr.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = NewConn(wc, log.DefaultLogger, &ConnMux{})
+	defer client.Close()
+
+	var expect = func(msgs ...string) {
+		for _, expected := range msgs {
+			if got := <-trail; got != expected {
+				t.Fatalf("expected %s, got %s", expected, got)
+			}
+		}
+	}
+
+	_ = client.Send(1, 1, nil)
+	expect("global")
+
+	_ = client.Send(1, 2, nil)
+	expect("global", "default")
+
+	_ = client.Send(2, 1, nil)
+	expect("global", "module", "default")
+}
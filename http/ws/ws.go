@@ -1,36 +1,66 @@
 package ws
 
 import (
+	"context"
 	"github.com/gorilla/websocket"
+	"github.com/oylshe1314/framework/errors"
 	"github.com/oylshe1314/framework/log"
 	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/metrics"
+	"github.com/oylshe1314/framework/trace"
 	"github.com/oylshe1314/framework/util"
 	"io"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const HeaderLength uint32 = 8
 
+const (
+	flagMetadata uint32 = 1 << 29 //the metadata block follows the header, see message.Metadata
+	lengthMask   uint32 = flagMetadata - 1
+)
+
 type Message struct {
-	ModId  uint16
-	MsgId  uint16
-	Length uint32
-	Body   []byte
+	ModId    uint16
+	MsgId    uint16
+	Length   uint32
+	Body     []byte
+	Metadata message.Metadata
 
 	Conn *Conn
+
+	ctx context.Context
 }
 
 func newMessage(modId, msgId uint16, length uint32, body []byte, conn *Conn) *Message {
 	return &Message{ModId: modId, MsgId: msgId, Length: length, Body: body, Conn: conn}
 }
 
+// Context returns the context of the message, it carries the trace span of the handler, and it is cancelled
+// when the connection is closed or the timeout of the handler is reached.
+func (this *Message) Context() context.Context {
+	if this.ctx == nil {
+		return this.Conn.Context()
+	}
+	return this.ctx
+}
+
+func (this *Message) logFields() log.Fields {
+	var fields = this.Conn.logFields(this.ModId, this.MsgId)
+	for key, value := range trace.Fields(this.Context()) {
+		fields[key] = value
+	}
+	return fields
+}
+
 func (this *Message) Read(v interface{}) error {
 	if v == nil || len(this.Body) == 0 {
 		if this.Conn.logger.IsDebugEnabled() {
 			if !this.Conn.isHeartbeat(this.ModId, this.MsgId) {
-				this.Conn.logger.Debugf("[%s:%d] <- ModId: %d, MsgId: %d, Msg: %s", this.Conn.RemoteAddr(), this.Conn.ObjectUid(), this.ModId, this.MsgId, util.ToJsonString(nil))
+				this.Conn.logger.WithFields(this.logFields()).Debug("<- ", util.ToJsonString(nil))
 			}
 		}
 		return nil
@@ -43,14 +73,14 @@ func (this *Message) Read(v interface{}) error {
 
 	if this.Conn.logger.IsDebugEnabled() {
 		if !this.Conn.isHeartbeat(this.ModId, this.MsgId) {
-			this.Conn.logger.Debugf("[%s:%d] <- ModId: %d, MsgId: %d, Msg: %s", this.Conn.RemoteAddr(), this.Conn.ObjectUid(), this.ModId, this.MsgId, util.ToJsonString(v))
+			this.Conn.logger.WithFields(this.logFields()).Debug("<- ", util.ToJsonString(v))
 		}
 	}
 	return nil
 }
 
 func (this *Message) Reply(v interface{}) error {
-	return this.Conn.Send(this.ModId, this.MsgId, v)
+	return this.Conn.sendMetadata(this.Context(), this.ModId, this.MsgId, nil, v)
 }
 
 type MessageHandler func(*Message)
@@ -65,7 +95,8 @@ type Handler interface {
 type Conn struct {
 	conn *websocket.Conn
 
-	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	locker sync.Mutex
 	logger log.Logger
@@ -74,6 +105,8 @@ type Conn struct {
 
 	object interface{}
 
+	handling atomic.Int32
+
 	beatTime   int64
 	beatPeriod int64
 	beatModId  uint16
@@ -81,13 +114,27 @@ type Conn struct {
 }
 
 func NewConn(conn *websocket.Conn, logger log.Logger, handler Handler) *Conn {
-	return &Conn{conn: conn, logger: logger, handler: handler}
+	var ctx, cancel = context.WithCancel(context.Background())
+	return &Conn{conn: conn, logger: logger, handler: handler, ctx: ctx, cancel: cancel}
+}
+
+// Context returns the context of the connection, it is cancelled when the connection is closed.
+func (this *Conn) Context() context.Context {
+	return this.ctx
+}
+
+func (this *Conn) isClosed() bool {
+	return this.ctx.Err() != nil
 }
 
 func (this *Conn) RemoteAddr() string {
 	return this.conn.RemoteAddr().String()
 }
 
+func (this *Conn) logFields(modId, msgId uint16) log.Fields {
+	return log.Fields{"remote": this.RemoteAddr(), "uid": this.ObjectUid(), "modId": modId, "msgId": msgId}
+}
+
 func (this *Conn) BindObject(object interface{}) {
 	this.object = object
 }
@@ -122,19 +169,58 @@ func (this *Conn) Read() (*Message, error) {
 		return nil, err
 	}
 
-	return newMessage(util.BytesToUint16(msg[0:2]), util.BytesToUint16(msg[2:4]), util.BytesToUint32(msg[4:8]), msg[8:], this), nil
+	if len(msg) < int(HeaderLength) {
+		return nil, errors.Errorf("message is too short, length: %d", len(msg))
+	}
+
+	var length = util.BytesToUint32(msg[4:8])
+	var body = msg[HeaderLength:]
+	var metadata message.Metadata
+	if length&flagMetadata != 0 {
+		if len(body) < message.MetadataLength {
+			return nil, errors.Error("invalid metadata block")
+		}
+
+		var size = int(util.BytesToUint16(body)) + message.MetadataLength
+		if len(body) < size {
+			return nil, errors.Error("invalid metadata block")
+		}
+
+		metadata, err = message.DecodeMetadata(body[message.MetadataLength:size])
+		if err != nil {
+			return nil, err
+		}
+		body = body[size:]
+	}
+
+	var m = newMessage(util.BytesToUint16(msg[0:2]), util.BytesToUint16(msg[2:4]), length&lengthMask, body, this)
+	m.Metadata = metadata
+	return m, nil
 }
 
-func (this *Conn) send(modId, msgId uint16, body []byte) (err error) {
-	var msg = make([]byte, HeaderLength+uint32(len(body)))
+func (this *Conn) send(modId, msgId uint16, metadata message.Metadata, body []byte) (err error) {
+	if uint32(len(body)) > lengthMask {
+		return errors.Errorf("message body is too long, length: %d", len(body))
+	}
+
+	var length = uint32(len(body))
+	var block []byte
+	if len(metadata) > 0 {
+		block, err = metadata.Encode()
+		if err != nil {
+			return err
+		}
+		length |= flagMetadata
+	}
+
+	var msg = make([]byte, HeaderLength, int(HeaderLength)+len(block)+len(body))
 
 	util.PutUint16ToBytes(msg[0:2], modId)
 	util.PutUint16ToBytes(msg[2:4], msgId)
-	util.PutUint32ToBytes(msg[4:8], uint32(len(body)))
+	util.PutUint32ToBytes(msg[4:8], length)
 
-	if len(body) > 0 {
-		copy(msg[8:], body)
-	}
+	msg = append(msg, block...)
+	msg = append(msg, body...)
 
 	this.locker.Lock()
 	defer this.locker.Unlock()
@@ -142,9 +228,23 @@ func (this *Conn) send(modId, msgId uint16, body []byte) (err error) {
 }
 
 func (this *Conn) Send(modId, msgId uint16, v interface{}) (err error) {
+	return this.sendMetadata(context.Background(), modId, msgId, nil, v)
+}
+
+// SendContext sends v with the trace context of ctx in the frame metadata, the peer must support the metadata extension.
+func (this *Conn) SendContext(ctx context.Context, modId, msgId uint16, v interface{}) (err error) {
+	var metadata message.Metadata
+	if traceparent := trace.Inject(ctx); len(traceparent) > 0 {
+		metadata = message.Metadata{trace.HeaderTraceparent: traceparent}
+	}
+	return this.sendMetadata(ctx, modId, msgId, metadata, v)
+}
+
+// sendMetadata encodes and sends v, the trace fields of ctx are logged with it.
+func (this *Conn) sendMetadata(ctx context.Context, modId, msgId uint16, metadata message.Metadata, v interface{}) (err error) {
 	if this.logger.IsDebugEnabled() {
 		if !this.isHeartbeat(modId, msgId) {
-			this.logger.Debugf("[%s:%d] -> ModId: %d, MsgId: %d, Msg: %s", this.RemoteAddr(), this.ObjectUid(), modId, msgId, util.ToJsonString(v))
+			this.logger.WithFields(this.logFields(modId, msgId)).WithFields(trace.Fields(ctx)).Debug("-> ", util.ToJsonString(v))
 		}
 	}
 
@@ -152,14 +252,14 @@ func (this *Conn) Send(modId, msgId uint16, v interface{}) (err error) {
 	if err != nil {
 		return err
 	}
-	return this.send(modId, msgId, body)
+	return this.send(modId, msgId, metadata, body)
 }
 
 func (this *Conn) Serve() error {
 	defer func() {
 		this.handler.handleWsDisconnect(this)
 
-		if this.closed {
+		if this.isClosed() {
 			return
 		}
 		_ = this.Close()
@@ -179,18 +279,25 @@ func (this *Conn) Serve() error {
 				return nil
 			}
 
-			if this.closed {
+			if this.isClosed() {
 				return nil
 			}
 
-			this.logger.Error("Read message failed, ", err)
+			this.logger.WithField("remote", this.RemoteAddr()).Error("Read message failed, ", err)
 			return err
 		}
 
+		this.handling.Add(1)
 		this.handler.handleWsMessage(msg)
+		this.handling.Add(-1)
 	}
 }
 
+// Idle returns whether the connection has no message being handled.
+func (this *Conn) Idle() bool {
+	return this.handling.Load() == 0
+}
+
 func (this *Conn) Beating(modId, msgId uint16, period int64) {
 	this.beatPeriod = period
 	this.beatModId = modId
@@ -204,22 +311,22 @@ func (this *Conn) Beating(modId, msgId uint16, period int64) {
 				this.logger.Error(string(debug.Stack()))
 			}
 		}()
-		this.logger.Infof("[%s] 心跳协程启动, time: %d", this.RemoteAddr(), this.beatTime)
-		for !this.closed {
+		this.logger.WithField("remote", this.RemoteAddr()).Infof("心跳协程启动, time: %d", this.beatTime)
+		for !this.isClosed() {
 			time.Sleep(time.Second)
 			var now = util.Unix()
 			if now-this.beatTime > period {
-				this.logger.Warnf("[%s] 连接心跳超时, time: %d", this.RemoteAddr(), this.beatTime)
+				this.logger.WithField("remote", this.RemoteAddr()).Warnf("连接心跳超时, time: %d", this.beatTime)
 				this.Close()
 				break
 			}
 		}
-		this.logger.Infof("[%s] 心跳协程退出, time: %d", this.RemoteAddr(), this.beatTime)
+		this.logger.WithField("remote", this.RemoteAddr()).Infof("心跳协程退出, time: %d", this.beatTime)
 	}()
 }
 
 func (this *Conn) Close() (err error) {
-	this.closed = true
+	this.cancel()
 	return this.conn.Close()
 }
 
@@ -229,7 +336,19 @@ type ConnMux struct {
 	connectHandler    func(*Conn)
 	disconnectHandler func(*Conn)
 	defaultHandler    MessageHandler
-	messageHandlers   map[uint32]MessageHandler
+	messageHandlers   map[uint32]*messageRoute
+
+	middlewares       []Middleware
+	moduleMiddlewares map[uint16][]Middleware
+
+	defaultChained MessageHandler
+	defaultChains  map[uint16]MessageHandler
+}
+
+type messageRoute struct {
+	handler MessageHandler
+	chained MessageHandler
+	timeout time.Duration
 }
 
 func (this *ConnMux) WsConnectHandler(handler func(*Conn)) {
@@ -240,15 +359,22 @@ func (this *ConnMux) WsDisconnectHandler(handler func(*Conn)) {
 	this.disconnectHandler = handler
 }
 
-func (this *ConnMux) WsMessageHandler(modId, msgId uint16, handler MessageHandler) {
+// WsMessageHandler registers the handler of the message, if the timeout is given, the context of the message
+// is cancelled after the timeout or when the handler returns.
+func (this *ConnMux) WsMessageHandler(modId, msgId uint16, handler MessageHandler, timeout ...time.Duration) {
 	if this.messageHandlers == nil {
-		this.messageHandlers = make(map[uint32]MessageHandler)
+		this.messageHandlers = make(map[uint32]*messageRoute)
 	}
-	this.messageHandlers[util.Compose2uint16(modId, msgId)] = handler
+	var route = &messageRoute{handler: handler, chained: this.chain(modId, handler)}
+	if len(timeout) > 0 {
+		route.timeout = timeout[0]
+	}
+	this.messageHandlers[util.Compose2uint16(modId, msgId)] = route
 }
 
 func (this *ConnMux) WsDefaultHandler(handler MessageHandler) {
 	this.defaultHandler = handler
+	this.rechain()
 }
 
 func (this *ConnMux) handleWsMessage(msg *Message) {
@@ -256,12 +382,30 @@ func (this *ConnMux) handleWsMessage(msg *Message) {
 		return
 	}
 
-	var handler = this.messageHandlers[util.Compose2uint16(msg.ModId, msg.MsgId)]
-	if handler != nil {
-		handler(msg)
+	var begin = time.Now()
+	var route = this.messageHandlers[util.Compose2uint16(msg.ModId, msg.MsgId)]
+	msg.ctx = trace.Extract(msg.Conn.Context(), msg.Metadata[trace.HeaderTraceparent])
+	if route != nil && route.timeout > 0 {
+		var cancel context.CancelFunc
+		msg.ctx, cancel = context.WithTimeout(msg.ctx, route.timeout)
+		defer cancel()
+	}
+
+	if !msg.Conn.isHeartbeat(msg.ModId, msg.MsgId) {
+		var span *trace.Span
+		msg.ctx, span = trace.Start(msg.ctx, "ws "+util.IntegerToString(msg.ModId)+"."+util.IntegerToString(msg.MsgId), trace.KindServer)
+		span.SetAttribute("remote", msg.Conn.RemoteAddr())
+		defer span.Finish()
+	}
+
+	// the messages are observed even if the handlers panic
+	if route != nil {
+		defer observeMessage(util.IntegerToString(msg.ModId), util.IntegerToString(msg.MsgId), begin)
+		route.chained(msg)
 	} else {
 		if this.defaultHandler != nil {
-			this.defaultHandler(msg)
+			defer observeMessage("*", "*", begin)
+			this.defaultChain(msg.ModId)(msg)
 		}
 	}
 }
@@ -288,3 +432,13 @@ func (this *ConnMux) getCodec() message.Codec {
 	}
 	return this.codec
 }
+
+var (
+	messagesTotal   = metrics.NewCounter("ws_messages_total", "The total websocket messages handled, the ids of the messages handled by the default handler are '*'.", "mod_id", "msg_id")
+	messageDuration = metrics.NewHistogram("ws_message_duration_seconds", "The latency of the websocket message handlers.", nil, "mod_id", "msg_id")
+)
+
+func observeMessage(modId, msgId string, begin time.Time) {
+	messagesTotal.Inc(modId, msgId)
+	messageDuration.Observe(time.Since(begin).Seconds(), modId, msgId)
+}
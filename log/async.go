@@ -0,0 +1,144 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The policies of the async writer when its buffer is full.
+const (
+	PolicyBlock     = "block"
+	PolicyDrop      = "drop"
+	PolicyDropDebug = "dropDebug"
+)
+
+const asyncBatchSize = 64 * 1024
+
+// WithAsync writes the logs asynchronously with a buffer of the count of entries, 0 means synchronously.
+func WithAsync(size int) Option {
+	return &logOption{name: "WithAsync", value: size}
+}
+
+// WithAsyncPolicy sets the policy when the async buffer is full, default PolicyBlock.
+func WithAsyncPolicy(policy string) Option {
+	return &logOption{name: "WithAsyncPolicy", value: policy}
+}
+
+func checkPolicy(policy string) error {
+	switch policy {
+	case PolicyBlock, PolicyDrop, PolicyDropDebug:
+		return nil
+	default:
+		return errors.Error("unsupported async policy: ", policy)
+	}
+}
+
+// asyncWriter writes the logs to out in batches by a goroutine.
+type asyncWriter struct {
+	out       io.Writer
+	policy    string
+	formatter logrus.Formatter
+
+	locker  sync.RWMutex
+	closed  bool
+	queue   chan []byte
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// newAsyncWriter returns an asyncWriter, the notices of the dropped logs are formatted by the formatter.
+func newAsyncWriter(out io.Writer, size int, policy string, formatter logrus.Formatter) *asyncWriter {
+	var writer = &asyncWriter{out: out, policy: policy, formatter: formatter, queue: make(chan []byte, size), done: make(chan struct{})}
+	go writer.run()
+	return writer
+}
+
+func (this *asyncWriter) output(entry *logrus.Entry, line []byte) {
+	_, _ = this.write(entry.Level, line)
+}
+
+func (this *asyncWriter) write(level logrus.Level, p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	if this.closed {
+		return this.out.Write(p)
+	}
+
+	// the buffer of the formatter may be reused after write returns
+	var buf = make([]byte, len(p))
+	copy(buf, p)
+
+	if this.policy == PolicyBlock || (this.policy == PolicyDropDebug && level < logrus.DebugLevel) {
+		this.queue <- buf
+		return len(p), nil
+	}
+
+	select {
+	case this.queue <- buf:
+	default:
+		this.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+func (this *asyncWriter) run() {
+	defer close(this.done)
+
+	var batch bytes.Buffer
+	for buf := range this.queue {
+		batch.Write(buf)
+	drain:
+		for batch.Len() < asyncBatchSize {
+			select {
+			case buf, ok := <-this.queue:
+				if !ok {
+					break drain
+				}
+				batch.Write(buf)
+			default:
+				break drain
+			}
+		}
+
+		if dropped := this.dropped.Swap(0); dropped > 0 {
+			var entry = &logrus.Entry{Data: logrus.Fields{}, Time: time.Now(), Level: logrus.WarnLevel, Message: fmt.Sprintf("%d log entries were dropped, the async buffer is full", dropped)}
+			line, err := this.formatter.Format(entry)
+			if err == nil {
+				batch.Write(line)
+			}
+		}
+
+		_, err := this.out.Write(batch.Bytes())
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		}
+		batch.Reset()
+	}
+}
+
+// Close flushes the buffered logs, the logs written after closing are written synchronously.
+func (this *asyncWriter) Close() error {
+	this.locker.Lock()
+	if this.closed {
+		this.locker.Unlock()
+		return nil
+	}
+	this.closed = true
+	close(this.queue)
+	this.locker.Unlock()
+
+	<-this.done
+	return nil
+}
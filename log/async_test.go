@@ -0,0 +1,57 @@
+package log
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type slowWriter struct {
+	locker sync.Mutex
+	buffer bytes.Buffer
+	delay  time.Duration
+}
+
+func (this *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(this.delay)
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.buffer.Write(p)
+}
+
+func (this *slowWriter) String() string {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.buffer.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	var out = &slowWriter{}
+	var writer = newAsyncWriter(out, 16, PolicyBlock, &logFormatter{})
+	for i := 0; i < 100; i++ {
+		_, _ = writer.write(logrus.InfoLevel, []byte("line\n"))
+	}
+	_ = writer.Close()
+
+	if n := strings.Count(out.String(), "line\n"); n != 100 {
+		t.Fatal("the logs were not flushed on closing, lines: ", n)
+	}
+
+	out = &slowWriter{delay: time.Millisecond * 10}
+	writer = newAsyncWriter(out, 1, PolicyDropDebug, &jsonFormatter{appName: "test", appId: 1})
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 5; j++ {
+			_, _ = writer.write(logrus.DebugLevel, []byte("debug\n"))
+		}
+		_, _ = writer.write(logrus.ErrorLevel, []byte("error\n"))
+	}
+	_ = writer.Close()
+
+	var s = out.String()
+	if strings.Count(s, "error\n") != 20 || strings.Count(s, "debug\n") == 100 || !strings.Contains(s, `"level":"WARN"`) || !strings.Contains(s, "were dropped") {
+		t.Fatal("unexpected output: ", s)
+	}
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	json "github.com/json-iterator/go"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Fields = logrus.Fields
+
+type Entry = logrus.Entry
+
+const (
+	FormatText   = "text"
+	FormatJson   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+func WithFormat(format string) Option {
+	return &logOption{name: "WithFormat", value: format}
+}
+
+func newFormatter(format, appName string, appId uint32) (logrus.Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return &logFormatter{}, nil
+	case FormatJson:
+		return &jsonFormatter{appName: appName, appId: appId}, nil
+	case FormatLogfmt:
+		return &logfmtFormatter{appName: appName, appId: appId}, nil
+	default:
+		return nil, errors.Error("unsupported log format: ", format)
+	}
+}
+
+func levelString(level logrus.Level) string {
+	var strLv = strings.ToUpper(level.String())
+	switch strLv {
+	case "WARNING":
+		strLv = "WARN"
+	case "UNKNOWN":
+		strLv = "INFO"
+	}
+	return strLv
+}
+
+func callerString(entry *logrus.Entry) string {
+	if entry.Caller == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", relativePath(entry.Caller.File), entry.Caller.Line)
+}
+
+func sortedKeys(fields logrus.Fields) []string {
+	var keys = make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fieldValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return v
+}
+
+type jsonFormatter struct {
+	appName string
+	appId   uint32
+}
+
+func (this *jsonFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	var data = make(map[string]interface{}, len(entry.Data)+6)
+	for key, value := range entry.Data {
+		data[key] = fieldValue(value)
+	}
+
+	data["time"] = entry.Time.Format("2006-01-02T15:04:05.000Z07:00")
+	data["level"] = levelString(entry.Level)
+	data["caller"] = callerString(entry)
+	data["app"] = this.appName
+	data["appId"] = this.appId
+	data["msg"] = strings.TrimRight(entry.Message, "\n")
+
+	buf, err := json.ConfigCompatibleWithStandardLibrary.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer = entry.Buffer
+	if buffer == nil {
+		buffer = &bytes.Buffer{}
+	}
+	buffer.Write(buf)
+	buffer.WriteByte('\n')
+	return buffer.Bytes(), nil
+}
+
+type logfmtFormatter struct {
+	appName string
+	appId   uint32
+}
+
+func writeLogfmt(buffer *bytes.Buffer, key string, value interface{}) {
+	if buffer.Len() > 0 {
+		buffer.WriteByte(' ')
+	}
+
+	var s, ok = value.(string)
+	if !ok {
+		s = fmt.Sprint(fieldValue(value))
+	}
+
+	buffer.WriteString(key)
+	buffer.WriteByte('=')
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		buffer.WriteString(strconv.Quote(s))
+	} else {
+		buffer.WriteString(s)
+	}
+}
+
+func (this *logfmtFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	var buffer = entry.Buffer
+	if buffer == nil {
+		buffer = &bytes.Buffer{}
+	}
+
+	writeLogfmt(buffer, "time", entry.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+	writeLogfmt(buffer, "level", levelString(entry.Level))
+	writeLogfmt(buffer, "caller", callerString(entry))
+	writeLogfmt(buffer, "app", this.appName)
+	writeLogfmt(buffer, "appId", this.appId)
+	writeLogfmt(buffer, "msg", strings.TrimRight(entry.Message, "\n"))
+	for _, key := range sortedKeys(entry.Data) {
+		writeLogfmt(buffer, key, entry.Data[key])
+	}
+	buffer.WriteByte('\n')
+	return buffer.Bytes(), nil
+}
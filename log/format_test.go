@@ -0,0 +1,44 @@
+package log
+
+import (
+	"github.com/sirupsen/logrus"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatters(t *testing.T) {
+	var entry = &logrus.Entry{
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:   logrus.InfoLevel,
+		Message: "hello world",
+		Data:    Fields{"modId": 1, "remote": "127.0.0.1:8101"},
+	}
+
+	var expected = map[string]string{
+		FormatText:   "[2024-01-02 03:04:05  INFO]                                                 # hello world modId=1 remote=127.0.0.1:8101\n",
+		FormatJson:   `{"app":"test","appId":1,"caller":"","level":"INFO","modId":1,"msg":"hello world","remote":"127.0.0.1:8101","time":"2024-01-02T03:04:05.000Z"}` + "\n",
+		FormatLogfmt: `time=2024-01-02T03:04:05.000Z level=INFO caller="" app=test appId=1 msg="hello world" modId=1 remote=127.0.0.1:8101` + "\n",
+	}
+
+	for format, line := range expected {
+		formatter, err := newFormatter(strings.ToUpper(format), "test", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf, err := formatter.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(buf) != line {
+			t.Fatalf("%s: %q, expected: %q", format, string(buf), line)
+		}
+	}
+
+	_, err := newFormatter("xml", "test", 1)
+	if err == nil {
+		t.Fatal("unsupported format was accepted")
+	}
+}
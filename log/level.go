@@ -0,0 +1,217 @@
+package log
+
+import (
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelPanic:
+		return "PANIC"
+	case LevelFatal:
+		return "FATAL"
+	case LevelError:
+		return "ERROR"
+	case LevelWarn:
+		return "WARN"
+	case LevelInfo:
+		return "INFO"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelTrace:
+		return "TRACE"
+	}
+	return "UNKNOWN"
+}
+
+// levels holds the level of a logger and the overrides of the caller packages, the level of the logrus logger
+// is set to the most verbose one, and the entries are filtered by the outputHook with their caller packages.
+type levels struct {
+	locker   sync.RWMutex
+	logger   *logrus.Logger
+	level    Level
+	packages map[string]Level
+}
+
+func newLevels(logger *logrus.Logger, level Level) *levels {
+	var lvs = &levels{logger: logger, level: level, packages: map[string]Level{}}
+	lvs.update()
+	return lvs
+}
+
+func (this *levels) update() {
+	var level = this.level
+	for _, lv := range this.packages {
+		if lv > level {
+			level = lv
+		}
+	}
+	this.logger.SetLevel(logrus.Level(level))
+}
+
+func (this *levels) LogLevel() Level {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+	return this.level
+}
+
+func (this *levels) SetLogLevel(level Level) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.level = level
+	this.update()
+}
+
+// SetPackageLevel overrides the level of the logs from the package, pkg is the import path or its last elements,
+// e.g. "net" or "framework/net".
+func (this *levels) SetPackageLevel(pkg string, level Level) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	this.packages[pkg] = level
+	this.update()
+}
+
+func (this *levels) RemovePackageLevel(pkg string) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	delete(this.packages, pkg)
+	this.update()
+}
+
+func (this *levels) PackageLevels() map[string]Level {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	var packages = make(map[string]Level, len(this.packages))
+	for pkg, lv := range this.packages {
+		packages[pkg] = lv
+	}
+	return packages
+}
+
+// callerPackage returns the import path of the package of a function name like "github.com/a/b.(*T).f".
+func callerPackage(function string) string {
+	var slash = strings.LastIndexByte(function, '/')
+	var dot = strings.IndexByte(function[slash+1:], '.')
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
+
+// packageLevel returns the level of the package with the longest matched override, the locker must be held.
+func (this *levels) packageLevel(pkg string) Level {
+	var level = this.level
+	var matched = ""
+	for p, lv := range this.packages {
+		if len(p) > len(matched) && (pkg == p || strings.HasSuffix(pkg, "/"+p)) {
+			matched = p
+			level = lv
+		}
+	}
+	return level
+}
+
+func (this *levels) enabled(entry *logrus.Entry) bool {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	if len(this.packages) == 0 || entry.Caller == nil {
+		return Level(entry.Level) <= this.level
+	}
+	return Level(entry.Level) <= this.packageLevel(callerPackage(entry.Caller.Function))
+}
+
+// logPackage is the import path of this package, its frames are skipped to find the caller of a logger.
+var logPackage = reflect.TypeOf(levels{}).PkgPath()
+
+// callerEnabled checks the level with the package of the function calling the logger.
+func (this *levels) callerEnabled(level Level) bool {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	if len(this.packages) == 0 {
+		return level <= this.level
+	}
+
+	var pcs [8]uintptr
+	var frames = runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if pkg := callerPackage(frame.Function); pkg != logPackage {
+			return level <= this.packageLevel(pkg)
+		}
+		if !more {
+			return level <= this.level
+		}
+	}
+}
+
+func (this *levels) IsDebugEnabled() bool {
+	return this.callerEnabled(LevelDebug)
+}
+
+func (this *levels) IsInfoEnabled() bool {
+	return this.callerEnabled(LevelInfo)
+}
+
+func (this *levels) IsWarnEnabled() bool {
+	return this.callerEnabled(LevelWarn)
+}
+
+func (this *levels) IsErrorEnabled() bool {
+	return this.callerEnabled(LevelError)
+}
+
+func (this *levels) IsFatalEnabled() bool {
+	return this.callerEnabled(LevelFatal)
+}
+
+func (this *levels) IsPanicEnabled() bool {
+	return this.callerEnabled(LevelPanic)
+}
+
+// output writes the formatted entries to a destination.
+type output interface {
+	output(entry *logrus.Entry, line []byte)
+}
+
+// outputHook filters the entries with the levels of their caller packages before formatting, then formats the
+// entry once and writes it to all the outputs. The logrus logger itself writes nothing.
+type outputHook struct {
+	levels    *levels
+	formatter logrus.Formatter
+	outputs   []output
+}
+
+func (this *outputHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (this *outputHook) Fire(entry *logrus.Entry) error {
+	if !this.levels.enabled(entry) {
+		return nil
+	}
+
+	line, err := this.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range this.outputs {
+		o.output(entry, line)
+	}
+	return nil
+}
+
+// noneFormatter is set to the logrus logger along with the noneWriter, the entries are formatted by the outputHook.
+type noneFormatter struct {
+}
+
+func (noneFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"github.com/sirupsen/logrus"
+	"runtime"
+	"testing"
+)
+
+func TestPackageLevels(t *testing.T) {
+	var lvs = newLevels(logrus.New(), LevelInfo)
+	lvs.SetPackageLevel("net", LevelDebug)
+	lvs.SetPackageLevel("framework/net/sub", LevelWarn)
+
+	if lvs.logger.GetLevel() != logrus.DebugLevel {
+		t.Fatal("the level of the logrus logger should be the most verbose one")
+	}
+
+	var cases = []struct {
+		function string
+		level    logrus.Level
+		enabled  bool
+	}{
+		{"github.com/oylshe1314/framework/net.(*Conn).Send", logrus.DebugLevel, true},
+		{"github.com/oylshe1314/framework/net/sub.f", logrus.InfoLevel, false},
+		{"github.com/oylshe1314/framework/server.(*NetServer).serve.func1", logrus.DebugLevel, false},
+		{"github.com/oylshe1314/framework/server.(*NetServer).serve.func1", logrus.InfoLevel, true},
+		{"main.main", logrus.InfoLevel, true},
+	}
+
+	for _, c := range cases {
+		var entry = &logrus.Entry{Level: c.level, Caller: &runtime.Frame{Function: c.function}}
+		if lvs.enabled(entry) != c.enabled {
+			t.Fatalf("%s %s: expected %v", c.function, c.level, c.enabled)
+		}
+	}
+
+	// the test functions are called by the testing package
+	if lvs.IsDebugEnabled() {
+		t.Fatal("debug should not be enabled out of the net package")
+	}
+	lvs.SetPackageLevel("testing", LevelDebug)
+	if !lvs.IsDebugEnabled() {
+		t.Fatal("debug should be enabled in the testing package")
+	}
+	lvs.RemovePackageLevel("testing")
+
+	lvs.RemovePackageLevel("net")
+	lvs.RemovePackageLevel("framework/net/sub")
+	if lvs.logger.GetLevel() != logrus.InfoLevel {
+		t.Fatal("the level of the logrus logger was not restored")
+	}
+}
+
+func TestLevelOf(t *testing.T) {
+	for _, level := range []Level{LevelTrace, LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal, LevelPanic} {
+		if LevelOf(level.String()) != level {
+			t.Fatalf("%s was not parsed", level)
+		}
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"io"
 	nativeLog "log"
+	"os"
 	"strings"
+	"time"
 )
 
 type Level uint32
@@ -39,6 +41,8 @@ func LevelOf(level string) Level {
 		return LevelInfo
 	case strings.EqualFold(level, "DEBUG"):
 		return LevelDebug
+	case strings.EqualFold(level, "TRACE"):
+		return LevelTrace
 	}
 	return 0xFFFFFFFF
 }
@@ -53,12 +57,18 @@ type Logger interface {
 	IsErrorEnabled() bool
 	IsFatalEnabled() bool
 	IsPanicEnabled() bool
+
+	LogLevel() Level
+	SetLogLevel(level Level)
+	SetPackageLevel(pkg string, level Level)
+	RemovePackageLevel(pkg string)
+	PackageLevels() map[string]Level
 }
 
 type logFormatter struct {
 }
 
-func (this *logFormatter) relativePath(file string) string {
+func relativePath(file string) string {
 	var p = strings.Index(file, "ecs/")
 	if p >= 0 {
 		return file[p+4:]
@@ -73,24 +83,17 @@ func (this *logFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 		buffer = &bytes.Buffer{}
 	}
 
-	var strLv = strings.ToUpper(entry.Level.String())
-	switch strLv {
-	case "WARNING":
-		strLv = "WARN"
-	case "UNKNOWN":
-		strLv = "INFO"
-	}
-
 	buffer.WriteString("[")
 	buffer.WriteString(entry.Time.Format("2006-01-02 15:04:05"))
 	buffer.WriteString(" ")
-	buffer.WriteString(fmt.Sprintf("%5s", strLv))
+	buffer.WriteString(fmt.Sprintf("%5s", levelString(entry.Level)))
 	buffer.WriteString("] ")
-	buffer.WriteString(fmt.Sprintf("%-47s # ", fmt.Sprintf("%s:%d", this.relativePath(entry.Caller.File), entry.Caller.Line)))
-	buffer.WriteString(entry.Message)
-	if buffer.Bytes()[buffer.Len()-1] != '\n' {
-		buffer.WriteByte('\n')
+	buffer.WriteString(fmt.Sprintf("%-47s # ", callerString(entry)))
+	buffer.WriteString(strings.TrimRight(entry.Message, "\n"))
+	for _, key := range sortedKeys(entry.Data) {
+		buffer.WriteString(fmt.Sprintf(" %s=%v", key, fieldValue(entry.Data[key])))
 	}
+	buffer.WriteByte('\n')
 
 	return buffer.Bytes(), nil
 }
@@ -122,7 +125,10 @@ func WithConsole(withConsole bool) Option {
 
 type dailyLogger struct {
 	*logrus.Logger
-	rl *frl.RotateLogs
+	*levels
+	rl      *frl.RotateLogs
+	writers []*asyncWriter
+	sinks   []*remoteSink
 }
 
 func NewDailyLogger(appName string, appId uint32, logDir string, opts ...Option) (Logger, error) {
@@ -132,6 +138,11 @@ func NewDailyLogger(appName string, appId uint32, logDir string, opts ...Option)
 
 	var lv = LevelInfo
 	var withConsole = false
+	var format = FormatText
+	var rotate rotateConfig
+	var asyncSize = 0
+	var asyncPolicy = PolicyBlock
+	var syslog, lineSink string
 
 	for _, opt := range opts {
 		switch opt.Name() {
@@ -139,53 +150,130 @@ func NewDailyLogger(appName string, appId uint32, logDir string, opts ...Option)
 			lv = opt.Value().(Level)
 		case "WithConsole":
 			withConsole = opt.Value().(bool)
+		case "WithFormat":
+			format = opt.Value().(string)
+		case "WithMaxSize":
+			rotate.maxSize = opt.Value().(int64)
+		case "WithMaxAge":
+			rotate.maxAge = opt.Value().(time.Duration)
+		case "WithMaxCount":
+			rotate.maxCount = opt.Value().(int)
+		case "WithCompress":
+			rotate.compress = opt.Value().(bool)
+		case "WithLinkName":
+			rotate.linkName = opt.Value().(string)
+		case "WithAsync":
+			asyncSize = opt.Value().(int)
+		case "WithAsyncPolicy":
+			asyncPolicy = opt.Value().(string)
+		case "WithSyslog":
+			syslog = opt.Value().(string)
+		case "WithLineSink":
+			lineSink = opt.Value().(string)
 		}
 	}
 
-	rl, err := frl.New(fmt.Sprintf("%s/%s_%d_%%Y-%%m-%%d.log", logDir, appName, appId), frl.WithLocation(util.UTC8()))
+	if asyncSize > 0 {
+		var err = checkPolicy(asyncPolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	formatter, err := newFormatter(format, appName, appId)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotate.maxAge <= 0 && rotate.maxCount <= 0 {
+		rotate.maxAge = defaultMaxAge
+	}
+
+	var prefix = fmt.Sprintf("%s_%d_", appName, appId)
+	var handler = &rotateHandler{rotateConfig: rotate, dir: logDir, prefix: prefix}
+	var rlOpts = append(rotate.options(), frl.WithLocation(util.UTC8()), frl.WithHandler(handler))
+	rl, err := frl.New(fmt.Sprintf("%s/%s%%Y-%%m-%%d.log", logDir, prefix), rlOpts...)
 	if err != nil {
 		return nil, err
 	}
+	handler.rl = rl
 
 	l := logrus.New()
+	var logger = &dailyLogger{Logger: l, rl: rl}
 
-	l.SetOutput(rl)
 	l.SetReportCaller(true)
-	l.SetLevel(logrus.Level(lv))
-	l.SetFormatter(&logFormatter{})
-	if withConsole {
-		l.AddHook(&consoleHook{})
-	}
+	l.SetOutput(noneWriter{})
+	l.SetFormatter(noneFormatter{})
+	logger.levels = newLevels(l, lv)
+	var hook = &outputHook{levels: logger.levels, formatter: formatter}
+
+	var console = &consoleOutput{}
+	if asyncSize > 0 {
+		var fileWriter = newAsyncWriter(rl, asyncSize, asyncPolicy, formatter)
+		hook.outputs = append(hook.outputs, fileWriter)
+		logger.writers = append(logger.writers, fileWriter)
+
+		if withConsole {
+			console.async = newAsyncWriter(os.Stdout, asyncSize, asyncPolicy, formatter)
+			logger.writers = append(logger.writers, console.async)
+		}
 
-	return &dailyLogger{Logger: l, rl: rl}, nil
-}
+		// the buffered logs are flushed before exiting with Fatal
+		l.ExitFunc = func(code int) {
+			_ = logger.Close()
+			os.Exit(code)
+		}
+	} else {
+		hook.outputs = append(hook.outputs, &writerOutput{Writer: rl})
+	}
 
-func (this *dailyLogger) Close() error {
-	return this.rl.Close()
-}
+	if withConsole {
+		hook.outputs = append(hook.outputs, console)
+	}
 
-func (this *dailyLogger) IsDebugEnabled() bool {
-	return this.Logger.IsLevelEnabled(logrus.Level(LevelDebug))
-}
+	if len(syslog) > 0 {
+		sink, err := newSyslogSink(syslog, appName)
+		if err != nil {
+			_ = logger.Close()
+			return nil, err
+		}
+		hook.outputs = append(hook.outputs, sink)
+		logger.sinks = append(logger.sinks, sink.remoteSink)
+	}
 
-func (this *dailyLogger) IsInfoEnabled() bool {
-	return this.Logger.IsLevelEnabled(logrus.Level(LevelInfo))
-}
+	if len(lineSink) > 0 {
+		sink, err := newLineSink(lineSink)
+		if err != nil {
+			_ = logger.Close()
+			return nil, err
+		}
+		hook.outputs = append(hook.outputs, sink)
+		logger.sinks = append(logger.sinks, sink.remoteSink)
+	}
 
-func (this *dailyLogger) IsWarnEnabled() bool {
-	return this.Logger.IsLevelEnabled(logrus.Level(LevelWarn))
+	l.AddHook(hook)
+	return logger, nil
 }
 
-func (this *dailyLogger) IsErrorEnabled() bool {
-	return this.Logger.IsLevelEnabled(logrus.Level(LevelError))
+func (this *dailyLogger) Close() error {
+	for _, sink := range this.sinks {
+		_ = sink.Close()
+	}
+	for _, writer := range this.writers {
+		_ = writer.Close()
+	}
+	return this.rl.Close()
 }
 
-func (this *dailyLogger) IsFatalEnabled() bool {
-	return this.Logger.IsLevelEnabled(logrus.Level(LevelFatal))
+type writerOutput struct {
+	io.Writer
 }
 
-func (this *dailyLogger) IsPanicEnabled() bool {
-	return this.Logger.IsLevelEnabled(logrus.Level(LevelPanic))
+func (this *writerOutput) output(_ *logrus.Entry, line []byte) {
+	_, err := this.Write(line)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+	}
 }
 
 type nativeLogWriter struct {
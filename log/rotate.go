@@ -0,0 +1,199 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	frl "github.com/lestrrat-go/file-rotatelogs"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultMaxAge = 7 * 24 * time.Hour
+
+// WithMaxSize rotates the log file to a numbered segment when it reaches the size in bytes.
+func WithMaxSize(maxSize int64) Option {
+	return &logOption{name: "WithMaxSize", value: maxSize}
+}
+
+// WithMaxAge removes the rotated log files older than the duration, default 7 days if WithMaxCount is not set.
+func WithMaxAge(maxAge time.Duration) Option {
+	return &logOption{name: "WithMaxAge", value: maxAge}
+}
+
+// WithMaxCount keeps at most the count of the rotated log files.
+func WithMaxCount(maxCount int) Option {
+	return &logOption{name: "WithMaxCount", value: maxCount}
+}
+
+// WithCompress compresses the rotated log files with gzip.
+func WithCompress(compress bool) Option {
+	return &logOption{name: "WithCompress", value: compress}
+}
+
+// WithLinkName links the name to the current log file.
+func WithLinkName(linkName string) Option {
+	return &logOption{name: "WithLinkName", value: linkName}
+}
+
+type rotateConfig struct {
+	maxSize  int64
+	maxAge   time.Duration
+	maxCount int
+	compress bool
+	linkName string
+}
+
+func (this *rotateConfig) options() []frl.Option {
+	var opts []frl.Option
+	if this.maxSize > 0 {
+		opts = append(opts, frl.WithRotationSize(this.maxSize))
+	}
+	if this.linkName != "" {
+		opts = append(opts, frl.WithLinkName(this.linkName))
+	}
+	// the rotatelogs accepts only one of the limits and falls back to 7 days without any, so the same limit is
+	// passed, the maxCount is left to the rotateHandler if both are set. It sees only the uncompressed files of
+	// the date pattern, a subset of the ones the rotateHandler counts, so it never removes a file the
+	// rotateHandler keeps.
+	if this.maxAge > 0 {
+		opts = append(opts, frl.WithMaxAge(this.maxAge))
+	} else {
+		opts = append(opts, frl.WithRotationCount(uint(this.maxCount)))
+	}
+	return opts
+}
+
+// rotateHandler compresses and cleans up the rotated log files with the prefix in the dir after every rotation.
+type rotateHandler struct {
+	rotateConfig
+
+	dir    string
+	prefix string
+	locker sync.Mutex
+
+	rl *frl.RotateLogs
+}
+
+func (this *rotateHandler) Handle(event frl.Event) {
+	if _, ok := event.(*frl.FileRotatedEvent); !ok {
+		return
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var files = this.rotatedFiles()
+	if this.compress {
+		for i, file := range files {
+			if strings.HasSuffix(file.name, ".gz") {
+				continue
+			}
+
+			target, err := gzipFile(file.name)
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Compress log file failed, %v\n", err)
+				continue
+			}
+			files[i].name = target
+		}
+	}
+
+	var cutoff = time.Now().Add(-this.maxAge)
+	for i, file := range files {
+		if (this.maxAge > 0 && file.modTime.Before(cutoff)) || (this.maxCount > 0 && i >= this.maxCount) {
+			_ = os.Remove(file.name)
+		}
+	}
+}
+
+type rotatedFile struct {
+	name    string
+	modTime time.Time
+}
+
+// rotatedFiles returns the log files except the current one, the newest first. The events are handled asynchronously,
+// so the current one is got from the rotatelogs instead of the event, it may have been rotated again.
+func (this *rotateHandler) rotatedFiles() []*rotatedFile {
+	var current = filepath.Clean(this.rl.CurrentFileName())
+	entries, err := os.ReadDir(this.dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []*rotatedFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasPrefix(entry.Name(), this.prefix) {
+			continue
+		}
+
+		var name = filepath.Join(this.dir, entry.Name())
+		if name == current || strings.HasSuffix(name, "_lock") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, &rotatedFile{name: name, modTime: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	return files
+}
+
+// gzipTarget returns a name which does not exist for the compressed file, the segment names of the rotatelogs may be
+// reused after the previous ones were compressed.
+func gzipTarget(name string) string {
+	var target = name + ".gz"
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			return target
+		}
+		target = fmt.Sprintf("%s.%d.gz", name, i)
+	}
+}
+
+func gzipFile(name string) (string, error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	var target = gzipTarget(name)
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	var gw = gzip.NewWriter(dst)
+	_, err = io.Copy(gw, src)
+	if err == nil {
+		err = gw.Close()
+	}
+	if err == nil {
+		err = dst.Close()
+	} else {
+		_ = dst.Close()
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return "", err
+	}
+
+	_ = os.Chtimes(target, info.ModTime(), info.ModTime())
+	return target, os.Remove(name)
+}
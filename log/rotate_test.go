@@ -0,0 +1,51 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotate(t *testing.T) {
+	var dir = t.TempDir()
+	var link = filepath.Join(dir, "test_1.log")
+	logger, err := NewDailyLogger("test", 1, dir, WithMaxSize(256), WithMaxCount(2), WithCompress(true), WithLinkName(link))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 4; j++ {
+			logger.Info(strings.Repeat("x", 64))
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	_ = logger.Close()
+	time.Sleep(time.Millisecond * 100)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var current, compressed int
+	for _, entry := range entries {
+		switch {
+		case entry.Name() == "test_1.log":
+		case strings.HasSuffix(entry.Name(), ".gz"):
+			compressed++
+		default:
+			current++
+		}
+	}
+
+	if current != 1 || compressed != 2 {
+		t.Fatalf("current: %d, compressed: %d", current, compressed)
+	}
+
+	if _, err = os.Readlink(link); err != nil {
+		t.Fatal(err)
+	}
+}
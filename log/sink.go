@@ -0,0 +1,242 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	sinkBufferSize   = 4096
+	sinkDialTimeout  = time.Second * 3
+	sinkWriteTimeout = time.Second * 3
+	sinkRetryMax     = time.Second * 30
+	sinkCloseTimeout = time.Second * 3
+)
+
+// WithSyslog sends the logs to a syslog server in RFC 5424, the address is like udp://127.0.0.1:514,
+// tcp://127.0.0.1:514 or unixgram:///dev/log.
+func WithSyslog(address string) Option {
+	return &logOption{name: "WithSyslog", value: address}
+}
+
+// WithLineSink sends the logs line by line to the address like tcp://127.0.0.1:5170 or udp://127.0.0.1:5170.
+func WithLineSink(address string) Option {
+	return &logOption{name: "WithLineSink", value: address}
+}
+
+func parseSinkAddress(address string) (network, addr string, err error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch u.Scheme {
+	case "tcp", "udp":
+		return u.Scheme, u.Host, nil
+	case "unix", "unixgram":
+		return u.Scheme, u.Path, nil
+	default:
+		return "", "", errors.Error("unsupported sink address: ", address)
+	}
+}
+
+// remoteSink sends the messages through a buffer by a goroutine, it reconnects with backoff if the connection
+// was broken, the messages are dropped if the buffer is full.
+type remoteSink struct {
+	network string
+	address string
+
+	locker  sync.RWMutex
+	closed  bool
+	queue   chan []byte
+	closing chan struct{}
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+func newRemoteSink(network, address string) *remoteSink {
+	var sink = &remoteSink{
+		network: network,
+		address: address,
+		queue:   make(chan []byte, sinkBufferSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go sink.run()
+	return sink
+}
+
+func (this *remoteSink) stream() bool {
+	return this.network == "tcp" || this.network == "unix"
+}
+
+func (this *remoteSink) send(msg []byte) {
+	this.locker.RLock()
+	defer this.locker.RUnlock()
+
+	if this.closed {
+		return
+	}
+
+	select {
+	case this.queue <- msg:
+	default:
+		this.dropped.Add(1)
+	}
+}
+
+func (this *remoteSink) wait(d time.Duration) bool {
+	select {
+	case <-this.closing:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func (this *remoteSink) run() {
+	defer close(this.done)
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	var retry = time.Duration(0)
+	var pending []byte
+	for {
+		if pending == nil {
+			msg, ok := <-this.queue
+			if !ok {
+				return
+			}
+			pending = msg
+		}
+
+		if conn == nil {
+			var err error
+			conn, err = net.DialTimeout(this.network, this.address, sinkDialTimeout)
+			if err != nil {
+				conn = nil
+				retry = min(max(retry*2, time.Second), sinkRetryMax)
+				if !this.wait(retry) {
+					return
+				}
+				continue
+			}
+			retry = 0
+
+			if dropped := this.dropped.Swap(0); dropped > 0 {
+				_, _ = fmt.Fprintf(os.Stderr, "%d log entries were dropped, the sink %s://%s was unavailable\n", dropped, this.network, this.address)
+			}
+		}
+
+		_ = conn.SetWriteDeadline(time.Now().Add(sinkWriteTimeout))
+		n, err := conn.Write(pending)
+		if err != nil {
+			_ = conn.Close()
+			conn = nil
+			// the rest of a partially written frame would corrupt the framing of the new connection
+			if n > 0 || !this.stream() {
+				this.dropped.Add(1)
+				pending = nil
+			}
+			continue
+		}
+		pending = nil
+	}
+}
+
+// Close sends the buffered messages in a limited time.
+func (this *remoteSink) Close() error {
+	this.locker.Lock()
+	if this.closed {
+		this.locker.Unlock()
+		return nil
+	}
+	this.closed = true
+	close(this.queue)
+	this.locker.Unlock()
+
+	select {
+	case <-this.done:
+	case <-time.After(sinkCloseTimeout):
+		close(this.closing)
+		<-this.done
+	}
+	return nil
+}
+
+var syslogSeverities = [...]int{0, 2, 3, 4, 6, 7, 7}
+
+// syslogSink sends the logs to a syslog server in RFC 5424, with the octet counting framing on the streams.
+type syslogSink struct {
+	*remoteSink
+
+	hostname string
+	appName  string
+	pid      int
+}
+
+func newSyslogSink(address string, appName string) (*syslogSink, error) {
+	network, addr, err := parseSinkAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogSink{remoteSink: newRemoteSink(network, addr), hostname: hostname, appName: appName, pid: os.Getpid()}, nil
+}
+
+// output sends the message and the fields of the entry only, the time, the level and the app are in the header.
+func (this *syslogSink) output(entry *logrus.Entry, _ []byte) {
+	var buffer bytes.Buffer
+	buffer.WriteString(strings.TrimRight(entry.Message, "\n"))
+	for _, key := range sortedKeys(entry.Data) {
+		writeLogfmt(&buffer, key, entry.Data[key])
+	}
+
+	// the facility is user-level messages
+	var pri = 1*8 + syslogSeverities[entry.Level]
+	var msg = fmt.Sprintf("<%d>1 %s %s %s %d - - %s", pri, entry.Time.Format(time.RFC3339Nano), this.hostname, this.appName, this.pid, buffer.String())
+	if this.stream() {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	this.send([]byte(msg))
+}
+
+// lineSink sends the logs line by line.
+type lineSink struct {
+	*remoteSink
+}
+
+func newLineSink(address string) (*lineSink, error) {
+	network, addr, err := parseSinkAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lineSink{remoteSink: newRemoteSink(network, addr)}, nil
+}
+
+func (this *lineSink) output(entry *logrus.Entry, line []byte) {
+	var msg = make([]byte, 0, len(line)+1)
+	msg = append(msg, strings.TrimRight(string(line), "\n")...)
+	msg = append(msg, '\n')
+	this.send(msg)
+}
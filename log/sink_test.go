@@ -0,0 +1,89 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineSink(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var lines = make(chan string, 16)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				var r = bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					lines <- line
+				}
+			}()
+		}
+	}()
+
+	logger, err := NewDailyLogger("test", 1, t.TempDir(), WithFormat(FormatLogfmt), WithLineSink("tcp://"+l.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.WithField("modId", 1).Info("hello")
+	logger.Debug("filtered")
+	logger.Warn("world")
+	_ = logger.Close()
+
+	for _, expected := range []string{"msg=hello modId=1\n", "msg=world\n"} {
+		select {
+		case line := <-lines:
+			if !strings.HasSuffix(line, expected) || !strings.Contains(line, "app=test appId=1") {
+				t.Fatalf("%q, expected: %q", line, expected)
+			}
+		case <-time.After(time.Second * 3):
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logger, err := NewDailyLogger("test", 1, t.TempDir(), WithSyslog("udp://"+conn.LocalAddr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.WithField("modId", 1).Error("hello")
+	_ = logger.Close()
+
+	var buf = make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 3))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var re = regexp.MustCompile(`^<11>1 \S+ \S+ test \d+ - - hello modId=1$`)
+	if !re.Match(buf[:n]) {
+		t.Fatalf("unexpected message: %q", buf[:n])
+	}
+}
@@ -0,0 +1,63 @@
+package message
+
+import (
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/util"
+	"sort"
+)
+
+// MetadataLength is the length of the size prefix of the metadata block in a frame.
+const MetadataLength = 2
+
+// Metadata is the key-value pairs carried by the frame metadata extension, the block is
+// encoded as a 2-byte size followed by the entries of 1-byte key length, key, 2-byte value length and value.
+type Metadata map[string]string
+
+// Encode returns the metadata block with the size prefix.
+func (md Metadata) Encode() ([]byte, error) {
+	var keys = make([]string, 0, len(md))
+	for key := range md {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf = make([]byte, MetadataLength, 64)
+	for _, key := range keys {
+		var value = md[key]
+		if len(key) == 0 || len(key) > 0xff || len(value) > 0xffff {
+			return nil, errors.Errorf("invalid metadata '%s'", key)
+		}
+
+		buf = append(buf, byte(len(key)))
+		buf = append(buf, key...)
+		buf = util.Uint16ToBytes(buf, uint16(len(value)))
+		buf = append(buf, value...)
+	}
+
+	if len(buf)-MetadataLength > 0xffff {
+		return nil, errors.Error("metadata is too long")
+	}
+	util.PutUint16ToBytes(buf, uint16(len(buf)-MetadataLength))
+	return buf, nil
+}
+
+// DecodeMetadata decodes the metadata block without the size prefix.
+func DecodeMetadata(block []byte) (Metadata, error) {
+	var md = Metadata{}
+	for len(block) > 0 {
+		var keyLen = int(block[0])
+		if len(block) < 1+keyLen+2 {
+			return nil, errors.Error("invalid metadata block")
+		}
+		var key = string(block[1 : 1+keyLen])
+		block = block[1+keyLen:]
+
+		var valueLen = int(util.BytesToUint16(block))
+		if len(block) < 2+valueLen {
+			return nil, errors.Error("invalid metadata block")
+		}
+		md[key] = string(block[2 : 2+valueLen])
+		block = block[2+valueLen:]
+	}
+	return md, nil
+}
@@ -0,0 +1,30 @@
+package message
+
+import (
+	"github.com/oylshe1314/framework/util"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	var md = Metadata{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "k": ""}
+	block, err := md.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if int(util.BytesToUint16(block)) != len(block)-MetadataLength {
+		t.Fatalf("unexpected size prefix %d", util.BytesToUint16(block))
+	}
+
+	decoded, err := DecodeMetadata(block[MetadataLength:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(md) || decoded["traceparent"] != md["traceparent"] || decoded["k"] != "" {
+		t.Fatalf("unexpected metadata %v", decoded)
+	}
+
+	if _, err = DecodeMetadata(block[MetadataLength : len(block)-1]); err == nil {
+		t.Fatal("the truncated block should be invalid")
+	}
+}
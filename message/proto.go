@@ -1,12 +1,40 @@
 package message
 
+// ModServer is the ModId reserved for the messages of the framework.
+const ModServer uint16 = 0xffff
+
+const (
+	MsgServerDetect uint16 = iota + 1
+)
+
 type MsgServerDetectAck struct {
 	ProgramHash string  `json:"programHash"`
 	DataHash    string  `json:"dataHash"`
 	ConfigHash  string  `json:"configHash"`
 	Pid         int     `json:"pid"`
-	CPU         float64 `json:"cpu"`
-	Memory      float64 `json:"memory"`
+	CPU         float64 `json:"cpu"`    //CPU usage percent
+	Memory      float64 `json:"memory"` //RSS percent of the total memory
 	Coroutine   int     `json:"coroutine"`
 	Info        string  `json:"info"`
+
+	Rss          uint64 `json:"rss,omitempty"` //bytes
+	HeapAlloc    uint64 `json:"heapAlloc,omitempty"`
+	HeapSys      uint64 `json:"heapSys,omitempty"`
+	HeapObjects  uint64 `json:"heapObjects,omitempty"`
+	NumGC        uint32 `json:"numGC,omitempty"`
+	GcPauseTotal uint64 `json:"gcPauseTotal,omitempty"` //nanoseconds
+	GcPauseLast  uint64 `json:"gcPauseLast,omitempty"`  //nanoseconds
+	Uptime       int64  `json:"uptime,omitempty"`       //seconds
+	Fds          int    `json:"fds,omitempty"`
+	Connections  int    `json:"connections,omitempty"`
+}
+
+type MsgServerLogLevelReq struct {
+	Package string `json:"package"`
+	Level   string `json:"level"`
+}
+
+type MsgServerLogLevelAck struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
 }
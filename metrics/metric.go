@@ -0,0 +1,262 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default histogram buckets in seconds, for the latency of the requests.
+var DefBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type value struct {
+	bits uint64
+}
+
+func (this *value) add(delta float64) {
+	for {
+		var old = atomic.LoadUint64(&this.bits)
+		if atomic.CompareAndSwapUint64(&this.bits, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (this *value) set(v float64) {
+	atomic.StoreUint64(&this.bits, math.Float64bits(v))
+}
+
+func (this *value) get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&this.bits))
+}
+
+type series[T any] struct {
+	values []string
+	data   *T
+}
+
+// family holds the series of a metric by the values of its labels.
+type family[T any] struct {
+	name   string
+	help   string
+	labels []string
+
+	locker sync.RWMutex
+	series map[string]*series[T]
+	newT   func() *T
+}
+
+func newFamily[T any](name, help string, labels []string, newT func() *T) *family[T] {
+	return &family[T]{name: name, help: help, labels: labels, series: map[string]*series[T]{}, newT: newT}
+}
+
+func (this *family[T]) Name() string {
+	return this.name
+}
+
+func (this *family[T]) Help() string {
+	return this.help
+}
+
+func (this *family[T]) with(values []string) *T {
+	if len(values) != len(this.labels) {
+		panic("metrics: '" + this.name + "' expects the values of the labels: " + strings.Join(this.labels, ", "))
+	}
+
+	var key = strings.Join(values, "\xff")
+	this.locker.RLock()
+	var s = this.series[key]
+	this.locker.RUnlock()
+	if s != nil {
+		return s.data
+	}
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	s = this.series[key]
+	if s == nil {
+		s = &series[T]{values: append([]string(nil), values...), data: this.newT()}
+		this.series[key] = s
+	}
+	return s.data
+}
+
+// Remove removes the series of the label values.
+func (this *family[T]) Remove(values ...string) {
+	this.locker.Lock()
+	delete(this.series, strings.Join(values, "\xff"))
+	this.locker.Unlock()
+}
+
+func (this *family[T]) sorted() []*series[T] {
+	this.locker.RLock()
+	var keys = make([]string, 0, len(this.series))
+	for key := range this.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var ss = make([]*series[T], 0, len(keys))
+	for _, key := range keys {
+		ss = append(ss, this.series[key])
+	}
+	this.locker.RUnlock()
+	return ss
+}
+
+type Counter struct {
+	*family[value]
+}
+
+// NewCounter registers a counter to the default registry, or returns the registered one of the same name.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return DefaultRegistry.NewCounter(name, help, labels...)
+}
+
+func (this *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return this.register(name, "counter", func() metric {
+		return &Counter{newFamily(name, help, labels, func() *value { return &value{} })}
+	}).(*Counter)
+}
+
+func (this *Counter) kind() string {
+	return "counter"
+}
+
+func (this *Counter) Inc(values ...string) {
+	this.with(values).add(1)
+}
+
+// Add adds delta to the counter, delta must not be negative.
+func (this *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+	this.with(values).add(delta)
+}
+
+func (this *Counter) Value(values ...string) float64 {
+	return this.with(values).get()
+}
+
+func (this *Counter) write(w *bufio.Writer) {
+	for _, s := range this.sorted() {
+		writeSample(w, this.name, this.labels, s.values, "", "", s.data.get())
+	}
+}
+
+type Gauge struct {
+	*family[value]
+}
+
+// NewGauge registers a gauge to the default registry, or returns the registered one of the same name.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help, labels...)
+}
+
+func (this *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return this.register(name, "gauge", func() metric {
+		return &Gauge{newFamily(name, help, labels, func() *value { return &value{} })}
+	}).(*Gauge)
+}
+
+func (this *Gauge) kind() string {
+	return "gauge"
+}
+
+func (this *Gauge) Set(v float64, values ...string) {
+	this.with(values).set(v)
+}
+
+func (this *Gauge) Add(delta float64, values ...string) {
+	this.with(values).add(delta)
+}
+
+func (this *Gauge) Inc(values ...string) {
+	this.with(values).add(1)
+}
+
+func (this *Gauge) Dec(values ...string) {
+	this.with(values).add(-1)
+}
+
+func (this *Gauge) Value(values ...string) float64 {
+	return this.with(values).get()
+}
+
+// Sum returns the sum of the values of all the series.
+func (this *Gauge) Sum() float64 {
+	var sum float64
+	for _, s := range this.sorted() {
+		sum += s.data.get()
+	}
+	return sum
+}
+
+func (this *Gauge) write(w *bufio.Writer) {
+	for _, s := range this.sorted() {
+		writeSample(w, this.name, this.labels, s.values, "", "", s.data.get())
+	}
+}
+
+type histogramData struct {
+	counts []uint64
+	count  uint64
+	sum    value
+}
+
+type Histogram struct {
+	*family[histogramData]
+	buckets []float64
+}
+
+// NewHistogram registers a histogram to the default registry, or returns the registered one of the same name,
+// the buckets are the upper bounds in ascending order, DefBuckets is used if it is empty.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return DefaultRegistry.NewHistogram(name, help, buckets, labels...)
+}
+
+func (this *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return this.register(name, "histogram", func() metric {
+		return &Histogram{
+			family:  newFamily(name, help, labels, func() *histogramData { return &histogramData{counts: make([]uint64, len(buckets))} }),
+			buckets: buckets,
+		}
+	}).(*Histogram)
+}
+
+func (this *Histogram) kind() string {
+	return "histogram"
+}
+
+func (this *Histogram) Observe(v float64, values ...string) {
+	var data = this.with(values)
+	var i = sort.SearchFloat64s(this.buckets, v)
+	if i < len(data.counts) {
+		atomic.AddUint64(&data.counts[i], 1)
+	}
+	data.sum.add(v)
+	atomic.AddUint64(&data.count, 1)
+}
+
+func (this *Histogram) write(w *bufio.Writer) {
+	for _, s := range this.sorted() {
+		var cumulative uint64
+		for i, upper := range this.buckets {
+			cumulative += atomic.LoadUint64(&s.data.counts[i])
+			writeSample(w, this.name+"_bucket", this.labels, s.values, "le", formatFloat(upper), float64(cumulative))
+		}
+		var count = atomic.LoadUint64(&s.data.count)
+		writeSample(w, this.name+"_bucket", this.labels, s.values, "le", "+Inf", float64(count))
+		writeSample(w, this.name+"_sum", this.labels, s.values, "", "", s.data.sum.get())
+		writeSample(w, this.name+"_count", this.labels, s.values, "", "", float64(count))
+	}
+}
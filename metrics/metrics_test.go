@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	var registry = NewRegistry()
+
+	var counter = registry.NewCounter("test_requests_total", "The total requests.", "code")
+	counter.Inc("200")
+	counter.Add(2, "200")
+	counter.Inc("5\"0\"0")
+
+	var gauge = registry.NewGauge("test_connections", "The current connections.")
+	registry.OnCollect(func() { gauge.Set(3) })
+
+	var histogram = registry.NewHistogram("test_duration_seconds", "The duration.", []float64{0.1, 1})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+
+	if registry.NewCounter("test_requests_total", "", "code") != counter {
+		t.Fatal("the registered counter should be returned")
+	}
+
+	var buf bytes.Buffer
+	_, err := registry.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = strings.Join([]string{
+		"# HELP test_connections The current connections.",
+		"# TYPE test_connections gauge",
+		"test_connections 3",
+		"# HELP test_duration_seconds The duration.",
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{le="0.1"} 1`,
+		`test_duration_seconds_bucket{le="1"} 2`,
+		`test_duration_seconds_bucket{le="+Inf"} 3`,
+		"test_duration_seconds_sum 5.55",
+		"test_duration_seconds_count 3",
+		"# HELP test_requests_total The total requests.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{code="200"} 3`,
+		`test_requests_total{code="5\"0\"0"} 1`,
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestGaugeSum(t *testing.T) {
+	var gauge = NewRegistry().NewGauge("test_connections", "The current connections.", "bind")
+	gauge.Add(2, ":8000")
+	gauge.Inc(":8001")
+	gauge.Dec(":8000")
+
+	if gauge.Sum() != 2 {
+		t.Fatalf("unexpected sum: %v", gauge.Sum())
+	}
+}
@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type metric interface {
+	Name() string
+	Help() string
+	kind() string
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	locker   sync.Mutex
+	metrics  map[string]metric
+	collects map[uint64]func()
+	nextId   uint64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]metric{}, collects: map[uint64]func(){}}
+}
+
+var DefaultRegistry = NewRegistry()
+
+// register adds the metric created by newMetric, or returns the registered one of the same name,
+// it panics if the registered one is not the same kind.
+func (this *Registry) register(name string, kind string, newMetric func() metric) metric {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var m = this.metrics[name]
+	if m != nil {
+		if m.kind() != kind {
+			panic("metrics: '" + name + "' was registered as a " + m.kind())
+		}
+		return m
+	}
+
+	m = newMetric()
+	this.metrics[name] = m
+	return m
+}
+
+// OnCollect calls fn before each exposition to update the gauges, the returned function removes it.
+func (this *Registry) OnCollect(fn func()) (remove func()) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	this.nextId++
+	var id = this.nextId
+	this.collects[id] = fn
+	return func() {
+		this.locker.Lock()
+		delete(this.collects, id)
+		this.locker.Unlock()
+	}
+}
+
+func (this *Registry) snapshot() ([]func(), []metric) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var ids = make([]uint64, 0, len(this.collects))
+	for id := range this.collects {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var collects = make([]func(), 0, len(ids))
+	for _, id := range ids {
+		collects = append(collects, this.collects[id])
+	}
+
+	var metrics = make([]metric, 0, len(this.metrics))
+	for _, m := range this.metrics {
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name() < metrics[j].Name() })
+	return collects, metrics
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format.
+func (this *Registry) WriteTo(w io.Writer) (int64, error) {
+	var collects, metrics = this.snapshot()
+	for _, collect := range collects {
+		collect()
+	}
+
+	var cw = &countWriter{w: w}
+	var bw = bufio.NewWriter(cw)
+	for _, m := range metrics {
+		_, _ = bw.WriteString("# HELP ")
+		_, _ = bw.WriteString(m.Name())
+		_ = bw.WriteByte(' ')
+		_, _ = bw.WriteString(escapeHelp(m.Help()))
+		_, _ = bw.WriteString("\n# TYPE ")
+		_, _ = bw.WriteString(m.Name())
+		_ = bw.WriteByte(' ')
+		_, _ = bw.WriteString(m.kind())
+		_ = bw.WriteByte('\n')
+		m.write(bw)
+	}
+	var err = bw.Flush()
+	return cw.n, err
+}
+
+func (this *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = this.WriteTo(w)
+}
+
+// Handler serves the metrics of the default registry.
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+func OnCollect(fn func()) (remove func()) {
+	return DefaultRegistry.OnCollect(fn)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (this *countWriter) Write(p []byte) (int, error) {
+	n, err := this.w.Write(p)
+	this.n += int64(n)
+	return n, err
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// writeSample writes a line of name{labels...,extraName="extraValue"} value.
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, value float64) {
+	_, _ = w.WriteString(name)
+	if len(labels) > 0 || len(extraName) > 0 {
+		_ = w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+			writeLabel(w, label, values[i])
+		}
+		if len(extraName) > 0 {
+			if len(labels) > 0 {
+				_ = w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		_ = w.WriteByte('}')
+	}
+	_ = w.WriteByte(' ')
+	_, _ = w.WriteString(formatFloat(value))
+	_ = w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	_, _ = w.WriteString(name)
+	_, _ = w.WriteString(`="`)
+	_, _ = w.WriteString(labelReplacer.Replace(value))
+	_ = w.WriteByte('"')
+}
+
+var goroutines = NewGauge("go_goroutines", "Number of goroutines that currently exist.")
+
+func init() {
+	OnCollect(func() {
+		goroutines.Set(float64(runtime.NumGoroutine()))
+	})
+}
@@ -1,25 +1,66 @@
 package net
 
 import (
+	"context"
+	"github.com/oylshe1314/framework/errors"
 	"github.com/oylshe1314/framework/log"
 	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/metrics"
+	"github.com/oylshe1314/framework/trace"
 	"github.com/oylshe1314/framework/util"
 	"io"
 	"net"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const HeaderLength uint32 = 8
 
+// SeqLength is the length of the extended header that carries the request sequence id,
+// it follows the 8-byte header when the sequence flag is set in the Length field.
+const SeqLength uint32 = 4
+
+const (
+	flagSequence uint32 = 1 << 31
+	flagResponse uint32 = 1 << 30
+	flagMetadata uint32 = 1 << 29 //the metadata block follows the header and the sequence id, see message.Metadata
+	lengthMask   uint32 = flagMetadata - 1
+)
+
+var ErrConnClosed = errors.Error("the connection was closed")
+
 type Message struct {
 	ModId  uint16
 	MsgId  uint16
 	Length uint32
 	Body   []byte
 
+	Seq      uint32
+	Response bool
+	Metadata message.Metadata
+
 	Conn *Conn
+
+	ctx context.Context
+}
+
+// Context returns the context of the message, it carries the trace span of the handler, and it is cancelled
+// when the connection is closed or the timeout of the handler is reached.
+func (this *Message) Context() context.Context {
+	if this.ctx == nil {
+		return this.Conn.Context()
+	}
+	return this.ctx
+}
+
+func (this *Message) logFields() log.Fields {
+	var fields = this.Conn.logFields(this.ModId, this.MsgId)
+	for key, value := range trace.Fields(this.Context()) {
+		fields[key] = value
+	}
+	return fields
 }
 
 func newMessage(modId, msgId uint16, length uint32, body []byte, conn *Conn) *Message {
@@ -30,7 +71,7 @@ func (this *Message) Read(v interface{}) error {
 	if v == nil || len(this.Body) == 0 {
 		if this.Conn.logger.IsDebugEnabled() {
 			if !this.Conn.isHeartbeat(this.ModId, this.MsgId) {
-				this.Conn.logger.Debugf("[%s:%d] <- ModId: %d, MsgId: %d, Msg: %s", this.Conn.RemoteAddr(), this.Conn.ObjectUid(), this.ModId, this.MsgId, util.ToJsonString(nil))
+				this.Conn.logger.WithFields(this.logFields()).Debug("<- ", util.ToJsonString(nil))
 			}
 		}
 		return nil
@@ -43,14 +84,16 @@ func (this *Message) Read(v interface{}) error {
 
 	if this.Conn.logger.IsDebugEnabled() {
 		if !this.Conn.isHeartbeat(this.ModId, this.MsgId) {
-			this.Conn.logger.Debugf("[%s:%d] <- ModId: %d, MsgId: %d, Msg: %s", this.Conn.RemoteAddr(), this.Conn.ObjectUid(), this.ModId, this.MsgId, util.ToJsonString(v))
+			this.Conn.logger.WithFields(this.logFields()).Debug("<- ", util.ToJsonString(v))
 		}
 	}
 	return nil
 }
 
+// Reply sends v back to the peer, the reply carries the sequence id of the message
+// so that a pending Call on the other side can be completed by it.
 func (this *Message) Reply(v interface{}) error {
-	return this.Conn.Send(this.ModId, this.MsgId, v)
+	return this.Conn.sendSeq(this.Context(), this.ModId, this.MsgId, this.Seq, this.Seq != 0, nil, v)
 }
 
 type MessageHandler func(*Message)
@@ -65,7 +108,8 @@ type Handler interface {
 type Conn struct {
 	conn net.Conn
 
-	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	locker sync.Mutex
 	logger log.Logger
@@ -74,6 +118,12 @@ type Conn struct {
 
 	object interface{}
 
+	seq     atomic.Uint32
+	pending map[uint32]chan *Message
+	plocker sync.Mutex
+
+	handling atomic.Int32
+
 	beatTime   int64
 	beatPeriod int64
 	beatModId  uint16
@@ -81,7 +131,17 @@ type Conn struct {
 }
 
 func NewConn(conn net.Conn, logger log.Logger, handler Handler) *Conn {
-	return &Conn{conn: conn, logger: logger, handler: handler}
+	var ctx, cancel = context.WithCancel(context.Background())
+	return &Conn{conn: conn, logger: logger, handler: handler, ctx: ctx, cancel: cancel}
+}
+
+// Context returns the context of the connection, it is cancelled when the connection is closed.
+func (this *Conn) Context() context.Context {
+	return this.ctx
+}
+
+func (this *Conn) isClosed() bool {
+	return this.ctx.Err() != nil
 }
 
 func (this *Conn) LocalAddr() string {
@@ -92,6 +152,10 @@ func (this *Conn) RemoteAddr() string {
 	return this.conn.RemoteAddr().String()
 }
 
+func (this *Conn) logFields(modId, msgId uint16) log.Fields {
+	return log.Fields{"remote": this.RemoteAddr(), "uid": this.ObjectUid(), "modId": modId, "msgId": msgId}
+}
+
 func (this *Conn) BindObject(object interface{}) {
 	this.object = object
 }
@@ -131,6 +195,38 @@ func (this *Conn) Read() (msg *Message, err error) {
 	var msgId = util.BytesToUint16(head[2:4])
 	var length = util.BytesToUint32(head[4:8])
 
+	var seq uint32
+	var flags = length &^ lengthMask
+	length &= lengthMask
+	if flags&flagSequence != 0 {
+		var ext = make([]byte, SeqLength)
+		_, err = io.ReadFull(this.conn, ext)
+		if err != nil {
+			return
+		}
+		seq = util.BytesToUint32(ext)
+	}
+
+	var metadata message.Metadata
+	if flags&flagMetadata != 0 {
+		var size = make([]byte, message.MetadataLength)
+		_, err = io.ReadFull(this.conn, size)
+		if err != nil {
+			return
+		}
+
+		var block = make([]byte, util.BytesToUint16(size))
+		_, err = io.ReadFull(this.conn, block)
+		if err != nil {
+			return
+		}
+
+		metadata, err = message.DecodeMetadata(block)
+		if err != nil {
+			return
+		}
+	}
+
 	var body []byte
 	if length > 0 {
 		body = make([]byte, length)
@@ -141,15 +237,43 @@ func (this *Conn) Read() (msg *Message, err error) {
 	}
 
 	msg = newMessage(modId, msgId, length, body, this)
+	msg.Seq = seq
+	msg.Response = flags&flagResponse != 0
+	msg.Metadata = metadata
 	return
 }
 
-func (this *Conn) send(modId, msgId uint16, body []byte) (err error) {
-	var head = make([]byte, HeaderLength)
+// send writes the frame, the metadata block follows the sequence id if the metadata is not empty.
+func (this *Conn) send(modId, msgId uint16, seq uint32, response bool, metadata message.Metadata, body []byte) (err error) {
+	if uint32(len(body)) > lengthMask {
+		return errors.Errorf("message body is too long, length: %d", len(body))
+	}
+
+	var length = uint32(len(body))
+	var head []byte
+	if seq == 0 {
+		head = make([]byte, HeaderLength)
+	} else {
+		head = make([]byte, HeaderLength+SeqLength)
+		length |= flagSequence
+		if response {
+			length |= flagResponse
+		}
+		util.PutUint32ToBytes(head[HeaderLength:], seq)
+	}
+
+	if len(metadata) > 0 {
+		block, err := metadata.Encode()
+		if err != nil {
+			return err
+		}
+		length |= flagMetadata
+		head = append(head, block...)
+	}
 
 	util.PutUint16ToBytes(head[0:2], modId)
 	util.PutUint16ToBytes(head[2:4], msgId)
-	util.PutUint32ToBytes(head[4:8], uint32(len(body)))
+	util.PutUint32ToBytes(head[4:8], length)
 
 	this.locker.Lock()
 	defer this.locker.Unlock()
@@ -167,10 +291,11 @@ func (this *Conn) send(modId, msgId uint16, body []byte) (err error) {
 	return err
 }
 
-func (this *Conn) Send(modId, msgId uint16, v interface{}) (err error) {
+// sendSeq encodes and sends v, the trace fields of ctx are logged with it.
+func (this *Conn) sendSeq(ctx context.Context, modId, msgId uint16, seq uint32, response bool, metadata message.Metadata, v interface{}) (err error) {
 	if this.logger.IsDebugEnabled() {
 		if !this.isHeartbeat(modId, msgId) {
-			this.logger.Debugf("[%s:%d] -> ModId: %d, MsgId: %d, Msg: %s", this.RemoteAddr(), this.ObjectUid(), modId, msgId, util.ToJsonString(v))
+			this.logger.WithFields(this.logFields(modId, msgId)).WithFields(trace.Fields(ctx)).WithField("seq", seq).Debug("-> ", util.ToJsonString(v))
 		}
 	}
 	body, err := this.handler.getCodec().Encode(v)
@@ -178,14 +303,152 @@ func (this *Conn) Send(modId, msgId uint16, v interface{}) (err error) {
 		this.logger.Error(err)
 		return err
 	}
-	return this.send(modId, msgId, body)
+	return this.send(modId, msgId, seq, response, metadata, body)
+}
+
+func (this *Conn) Send(modId, msgId uint16, v interface{}) (err error) {
+	return this.sendSeq(context.Background(), modId, msgId, 0, false, nil, v)
+}
+
+// SendContext sends v with the trace context of ctx in the frame metadata, the peer must support the metadata extension.
+func (this *Conn) SendContext(ctx context.Context, modId, msgId uint16, v interface{}) (err error) {
+	return this.sendSeq(ctx, modId, msgId, 0, false, traceMetadata(ctx), v)
+}
+
+func traceMetadata(ctx context.Context) message.Metadata {
+	var traceparent = trace.Inject(ctx)
+	if len(traceparent) == 0 {
+		return nil
+	}
+	return message.Metadata{trace.HeaderTraceparent: traceparent}
+}
+
+func (this *Conn) nextSeq() uint32 {
+	for {
+		var seq = this.seq.Add(1)
+		if seq != 0 {
+			return seq
+		}
+	}
+}
+
+// Call sends req with a new sequence id and blocks until the reply with the same
+// sequence id arrives or ctx is done, the reply is decoded into res.
+// The replies are dispatched by Serve, so it must be running on the connection, a handler of the connection
+// may Call on it as the handlers don't block the dispatching.
+// The call is traced by a client span, its context is sent in the frame metadata.
+func (this *Conn) Call(ctx context.Context, modId, msgId uint16, req, res interface{}) (err error) {
+	ctx, span := trace.Start(ctx, spanName(modId, msgId), trace.KindClient)
+	span.SetAttribute("remote", this.RemoteAddr())
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+
+	var seq = this.nextSeq()
+	var ch = make(chan *Message, 1)
+
+	this.plocker.Lock()
+	if this.isClosed() {
+		this.plocker.Unlock()
+		return ErrConnClosed
+	}
+	if this.pending == nil {
+		this.pending = make(map[uint32]chan *Message)
+	}
+	this.pending[seq] = ch
+	this.plocker.Unlock()
+
+	err = this.sendSeq(ctx, modId, msgId, seq, false, traceMetadata(ctx), req)
+	if err != nil {
+		this.removePending(seq)
+		return err
+	}
+
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			return ErrConnClosed
+		}
+		return msg.Read(res)
+	case <-ctx.Done():
+		this.removePending(seq)
+		return ctx.Err()
+	}
 }
 
+func (this *Conn) removePending(seq uint32) {
+	this.plocker.Lock()
+	delete(this.pending, seq)
+	this.plocker.Unlock()
+}
+
+func (this *Conn) handleResponse(msg *Message) {
+	this.plocker.Lock()
+	var ch = this.pending[msg.Seq]
+	delete(this.pending, msg.Seq)
+	this.plocker.Unlock()
+
+	if ch == nil {
+		this.logger.WithFields(this.logFields(msg.ModId, msg.MsgId)).WithField("seq", msg.Seq).Warn("The response has no pending call")
+		return
+	}
+	ch <- msg
+}
+
+func (this *Conn) clearPending() {
+	this.plocker.Lock()
+	for seq, ch := range this.pending {
+		close(ch)
+		delete(this.pending, seq)
+	}
+	this.plocker.Unlock()
+}
+
+// handleQueueSize is the max count of the messages read but not handled of a connection.
+const handleQueueSize = 1024
+
+// handle calls the handlers of the messages in order, the connection is closed if a handler panics.
+func (this *Conn) handle(messages chan *Message, done chan struct{}) {
+	defer close(done)
+	defer func() {
+		var err = recover()
+		if err != nil {
+			this.logger.Error(err)
+			this.logger.Error(string(debug.Stack()))
+			_ = this.Close()
+
+			// unblock the read loop until it sees the connection closed
+			for range messages {
+			}
+		}
+	}()
+
+	for msg := range messages {
+		this.handler.handleMessage(msg)
+		this.handling.Add(-1)
+	}
+}
+
+// Idle returns whether the connection has no messages being handled or waiting to be handled.
+func (this *Conn) Idle() bool {
+	return this.handling.Load() == 0
+}
+
+// Serve reads the messages until the connection is closed. The handlers are called in order on a goroutine
+// apart from the read loop, so that the replies are still dispatched while a handler is blocked, a handler can
+// Call on its own connection unless the queue of the unhandled messages is full.
 func (this *Conn) Serve() error {
+	var messages = make(chan *Message, handleQueueSize)
+	var handled = make(chan struct{})
+	go this.handle(messages, handled)
+
 	defer func() {
+		close(messages)
+		<-handled
 		this.handler.handleDisconnect(this)
 
-		if this.closed {
+		if this.isClosed() {
 			return
 		}
 		_ = this.Close()
@@ -205,15 +468,21 @@ func (this *Conn) Serve() error {
 				return nil
 			}
 
-			if this.closed {
+			if this.isClosed() {
 				return nil
 			}
 
-			this.logger.Error("Read message failed, ", err)
+			this.logger.WithField("remote", this.RemoteAddr()).Error("Read message failed, ", err)
 			return err
 		}
 
-		this.handler.handleMessage(msg)
+		if msg.Response {
+			this.handleResponse(msg)
+			continue
+		}
+
+		this.handling.Add(1)
+		messages <- msg
 	}
 }
 
@@ -230,22 +499,26 @@ func (this *Conn) Beating(modId, msgId uint16, period int64) {
 				this.logger.Error(string(debug.Stack()))
 			}
 		}()
-		this.logger.Infof("[%s] 心跳协程启动, time: %d", this.RemoteAddr(), this.beatTime)
-		for !this.closed {
+		this.logger.WithField("remote", this.RemoteAddr()).Infof("心跳协程启动, time: %d", this.beatTime)
+		for !this.isClosed() {
 			time.Sleep(time.Second)
 			var now = util.Unix()
 			if now-this.beatTime > period {
-				this.logger.Warnf("[%s] 连接心跳超时, time: %d", this.RemoteAddr(), this.beatTime)
+				this.logger.WithField("remote", this.RemoteAddr()).Warnf("连接心跳超时, time: %d", this.beatTime)
 				this.Close()
 				break
 			}
 		}
-		this.logger.Infof("[%s] 心跳协程退出, time: %d", this.RemoteAddr(), this.beatTime)
+		this.logger.WithField("remote", this.RemoteAddr()).Infof("心跳协程退出, time: %d", this.beatTime)
 	}()
 }
 
 func (this *Conn) Close() (err error) {
-	this.closed = true
+	this.plocker.Lock()
+	this.cancel()
+	this.plocker.Unlock()
+
+	this.clearPending()
 	return this.conn.Close()
 }
 
@@ -255,7 +528,19 @@ type ConnMux struct {
 	connectHandler    func(*Conn)
 	disconnectHandler func(*Conn)
 	defaultHandler    MessageHandler
-	messageHandlers   map[uint32]MessageHandler
+	messageHandlers   map[uint32]*messageRoute
+
+	middlewares       []Middleware
+	moduleMiddlewares map[uint16][]Middleware
+
+	defaultChained MessageHandler
+	defaultChains  map[uint16]MessageHandler
+}
+
+type messageRoute struct {
+	handler MessageHandler
+	chained MessageHandler
+	timeout time.Duration
 }
 
 func (this *ConnMux) ConnectHandler(handler func(*Conn)) {
@@ -266,15 +551,22 @@ func (this *ConnMux) DisconnectHandler(handler func(*Conn)) {
 	this.disconnectHandler = handler
 }
 
-func (this *ConnMux) MessageHandler(modId, msgId uint16, handler MessageHandler) {
+// MessageHandler registers the handler of the message, if the timeout is given, the context of the message
+// is cancelled after the timeout or when the handler returns.
+func (this *ConnMux) MessageHandler(modId, msgId uint16, handler MessageHandler, timeout ...time.Duration) {
 	if this.messageHandlers == nil {
-		this.messageHandlers = make(map[uint32]MessageHandler)
+		this.messageHandlers = make(map[uint32]*messageRoute)
 	}
-	this.messageHandlers[util.Compose2uint16(modId, msgId)] = handler
+	var route = &messageRoute{handler: handler, chained: this.chain(modId, handler)}
+	if len(timeout) > 0 {
+		route.timeout = timeout[0]
+	}
+	this.messageHandlers[util.Compose2uint16(modId, msgId)] = route
 }
 
 func (this *ConnMux) DefaultHandler(handler MessageHandler) {
 	this.defaultHandler = handler
+	this.rechain()
 }
 
 func (this *ConnMux) handleMessage(msg *Message) {
@@ -282,12 +574,30 @@ func (this *ConnMux) handleMessage(msg *Message) {
 		return
 	}
 
-	var handler = this.messageHandlers[util.Compose2uint16(msg.ModId, msg.MsgId)]
-	if handler != nil {
-		handler(msg)
+	var begin = time.Now()
+	var route = this.messageHandlers[util.Compose2uint16(msg.ModId, msg.MsgId)]
+	msg.ctx = trace.Extract(msg.Conn.Context(), msg.Metadata[trace.HeaderTraceparent])
+	if route != nil && route.timeout > 0 {
+		var cancel context.CancelFunc
+		msg.ctx, cancel = context.WithTimeout(msg.ctx, route.timeout)
+		defer cancel()
+	}
+
+	if !msg.Conn.isHeartbeat(msg.ModId, msg.MsgId) {
+		var span *trace.Span
+		msg.ctx, span = trace.Start(msg.ctx, spanName(msg.ModId, msg.MsgId), trace.KindServer)
+		span.SetAttribute("remote", msg.Conn.RemoteAddr())
+		defer span.Finish()
+	}
+
+	// the messages are observed even if the handlers panic
+	if route != nil {
+		defer observeMessage(util.IntegerToString(msg.ModId), util.IntegerToString(msg.MsgId), begin)
+		route.chained(msg)
 	} else {
 		if this.defaultHandler != nil {
-			this.defaultHandler(msg)
+			defer observeMessage("*", "*", begin)
+			this.defaultChain(msg.ModId)(msg)
 		}
 	}
 }
@@ -314,3 +624,17 @@ func (this *ConnMux) getCodec() message.Codec {
 	}
 	return this.codec
 }
+
+var (
+	messagesTotal   = metrics.NewCounter("net_messages_total", "The total net messages handled, the ids of the messages handled by the default handler are '*'.", "mod_id", "msg_id")
+	messageDuration = metrics.NewHistogram("net_message_duration_seconds", "The latency of the net message handlers.", nil, "mod_id", "msg_id")
+)
+
+func spanName(modId, msgId uint16) string {
+	return "net " + util.IntegerToString(modId) + "." + util.IntegerToString(msgId)
+}
+
+func observeMessage(modId, msgId string, begin time.Time) {
+	messagesTotal.Inc(modId, msgId)
+	messageDuration.Observe(time.Since(begin).Seconds(), modId, msgId)
+}
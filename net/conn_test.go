@@ -0,0 +1,194 @@
+package net
+
+import (
+	"context"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/trace"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnCall(t *testing.T) {
+	c1, c2 := net.Pipe()
+
+	var serverMux = &ConnMux{}
+	serverMux.SetCodec(message.NewJsonCodec())
+	serverMux.MessageHandler(1, 1, func(msg *Message) {
+		var req string
+		if err := msg.Read(&req); err != nil {
+			t.Error(err)
+			return
+		}
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			_ = msg.Reply("re: " + req)
+		}()
+	})
+
+	var notified = make(chan string, 1)
+	var clientMux = &ConnMux{}
+	clientMux.SetCodec(message.NewJsonCodec())
+	clientMux.MessageHandler(1, 2, func(msg *Message) {
+		var s string
+		_ = msg.Read(&s)
+		notified <- s
+	})
+
+	var server = NewConn(c1, log.DefaultLogger, serverMux)
+	var client = NewConn(c2, log.DefaultLogger, clientMux)
+	go server.Serve()
+	go client.Serve()
+	defer client.Close()
+	defer server.Close()
+
+	var results = make(chan error, 3)
+	for _, req := range []string{"a", "b", "c"} {
+		go func(req string) {
+			var res string
+			var err = client.Call(context.Background(), 1, 1, req, &res)
+			if err == nil && res != "re: "+req {
+				t.Errorf("unexpected reply %q for %q", res, req)
+			}
+			results <- err
+		}(req)
+	}
+
+	for range 3 {
+		if err := <-results; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := server.Send(1, 2, "notify"); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-notified; s != "notify" {
+		t.Errorf("unexpected notify %q", s)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	var res string
+	if err := client.Call(ctx, 1, 3, "timeout", &res); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestConnTrace(t *testing.T) {
+	c1, c2 := net.Pipe()
+
+	var received = make(chan trace.SpanContext, 1)
+	var serverMux = &ConnMux{}
+	serverMux.MessageHandler(1, 1, func(msg *Message) {
+		if span := trace.SpanFromContext(msg.Context()); span != nil {
+			if msg.Seq != 0 {
+				_ = msg.Reply(span.Parent.String())
+				return
+			}
+			received <- span.SpanContext
+		}
+	})
+
+	var server = NewConn(c1, log.DefaultLogger, serverMux)
+	var client = NewConn(c2, log.DefaultLogger, &ConnMux{})
+	go server.Serve()
+	go client.Serve()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, span := trace.Start(context.Background(), "test", trace.KindInternal)
+	if err := client.SendContext(ctx, 1, 1, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if sc := <-received; sc.TraceId != span.TraceId {
+		t.Errorf("unexpected trace id %s, expected %s", sc.TraceId, span.TraceId)
+	}
+
+	var parent string
+	if err := client.Call(ctx, 1, 1, "b", &parent); err != nil {
+		t.Fatal(err)
+	}
+	if parent == span.SpanId.String() || len(parent) != 16 {
+		t.Errorf("the parent of the server span should be the client span of the call, got %q", parent)
+	}
+}
+
+func TestConnContext(t *testing.T) {
+	c1, c2 := net.Pipe()
+
+	var done = make(chan error, 2)
+	var serverMux = &ConnMux{}
+	serverMux.MessageHandler(1, 1, func(msg *Message) {
+		<-msg.Context().Done()
+		done <- msg.Context().Err()
+	}, time.Millisecond*10)
+	serverMux.MessageHandler(1, 2, func(msg *Message) {
+		go func() {
+			<-msg.Context().Done()
+			done <- msg.Context().Err()
+		}()
+	})
+
+	var server = NewConn(c1, log.DefaultLogger, serverMux)
+	var client = NewConn(c2, log.DefaultLogger, &ConnMux{})
+	go server.Serve()
+	go client.Serve()
+	defer client.Close()
+
+	if err := client.Send(1, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if err := client.Send(1, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 10)
+	_ = server.Close()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected canceled after the connection closed, got %v", err)
+	}
+}
+
+func TestConnReentrantCall(t *testing.T) {
+	c1, c2 := net.Pipe()
+
+	var serverMux = &ConnMux{}
+	serverMux.SetCodec(message.NewJsonCodec())
+	serverMux.MessageHandler(1, 1, func(msg *Message) {
+		var name string
+		if err := msg.Conn.Call(msg.Context(), 2, 1, nil, &name); err != nil {
+			t.Error(err)
+			return
+		}
+		_ = msg.Reply("hello " + name)
+	})
+
+	var clientMux = &ConnMux{}
+	clientMux.SetCodec(message.NewJsonCodec())
+	clientMux.MessageHandler(2, 1, func(msg *Message) {
+		_ = msg.Reply("client")
+	})
+
+	var server = NewConn(c1, log.DefaultLogger, serverMux)
+	var client = NewConn(c2, log.DefaultLogger, clientMux)
+	go server.Serve()
+	go client.Serve()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	var res string
+	if err := client.Call(ctx, 1, 1, nil, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res != "hello client" {
+		t.Fatalf("unexpected reply %q", res)
+	}
+}
@@ -0,0 +1,98 @@
+package net
+
+import (
+	"context"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/message"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	c1, c2 := net.Pipe()
+
+	var trail = make(chan string, 8)
+	var mark = func(name string) Middleware {
+		return func(next MessageHandler) MessageHandler {
+			return func(msg *Message) {
+				trail <- name
+				next(msg)
+			}
+		}
+	}
+
+	var serverMux = &ConnMux{}
+	serverMux.SetCodec(message.NewJsonCodec())
+	serverMux.Use(Recover(), mark("global"))
+	serverMux.UseModule(2, mark("module"), AuthRequired(func(msg *Message) { trail <- "denied" }))
+	serverMux.MessageHandler(1, 1, func(msg *Message) { panic("boom") })
+	serverMux.MessageHandler(1, 2, func(msg *Message) { trail <- "handler" })
+	serverMux.MessageHandler(2, 1, func(msg *Message) { trail <- "handler" })
+
+	var server = NewConn(c1, log.DefaultLogger, serverMux)
+	var clientMux = &ConnMux{}
+	clientMux.SetCodec(message.NewJsonCodec())
+	var client = NewConn(c2, log.DefaultLogger, clientMux)
+	go server.Serve()
+	go client.Serve()
+	defer client.Close()
+	defer server.Close()
+
+	var expect = func(msgs ...string) {
+		for _, expected := range msgs {
+			if got := <-trail; got != expected {
+				t.Fatalf("expected %s, got %s", expected, got)
+			}
+		}
+	}
+
+	_ = client.Send(1, 1, nil)
+	expect("global")
+
+	_ = client.Send(1, 2, nil)
+	expect("global", "handler")
+
+	_ = client.Send(2, 1, nil)
+	expect("global", "module", "denied")
+
+	server.BindObject(struct{}{})
+	_ = client.Send(2, 1, nil)
+	expect("global", "module", "handler")
+
+	var reply message.Reply
+	if err := client.Call(context.Background(), 1, 1, nil, &reply); err != nil {
+		t.Fatal(err)
+	}
+	expect("global")
+	if reply.Succeed() {
+		t.Fatal("the panic of a call should be replied with an error")
+	}
+
+	if len(trail) > 0 {
+		var rest []string
+		for len(trail) > 0 {
+			rest = append(rest, <-trail)
+		}
+		t.Fatalf("unexpected trail: %s", strings.Join(rest, ", "))
+	}
+}
+
+func TestObservePanic(t *testing.T) {
+	c1, _ := net.Pipe()
+
+	var mux = &ConnMux{}
+	mux.MessageHandler(3, 1, func(msg *Message) { panic("boom") })
+	var conn = NewConn(c1, log.DefaultLogger, mux)
+	defer conn.Close()
+
+	var observed = messagesTotal.Value("3", "1")
+	func() {
+		defer func() { _ = recover() }()
+		mux.handleMessage(&Message{Conn: conn, ModId: 3, MsgId: 1})
+	}()
+
+	if messagesTotal.Value("3", "1") != observed+1 {
+		t.Fatal("the panicking message should be observed")
+	}
+}
@@ -0,0 +1,45 @@
+package options
+
+import (
+	"os"
+	"strings"
+)
+
+// ReadEnv reads the environment variables with the prefix as options, the levels of an option are separated
+// by "__", e.g. APP_NETSERVER__BIND=:8101 with the prefix "APP" is netServer.bind. The names are matched with
+// the existing options case-insensitively, so that the returned options can be merged into them.
+func ReadEnv(prefix string, options Options) Options {
+	var envOptions = map[string]interface{}{}
+	if len(prefix) == 0 {
+		return envOptions
+	}
+
+	prefix = prefix + "_"
+	for _, env := range os.Environ() {
+		var idx = strings.IndexByte(env, '=')
+		if idx <= len(prefix) || !strings.HasPrefix(env, prefix) {
+			continue
+		}
+
+		var chains = strings.Split(env[len(prefix):idx], "__")
+		resolveChains(chains, options)
+		setFieldChain(chains, 0, env[idx+1:], envOptions)
+	}
+	return envOptions
+}
+
+func resolveChains(chains []string, options Options) {
+	for i, name := range chains {
+		var key = options.lookupKey(name)
+		if key == "" {
+			return
+		}
+
+		chains[i] = key
+		subOptions, ok := options[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		options = subOptions
+	}
+}
@@ -30,6 +30,26 @@ func (options Options) Get(name string) interface{} {
 	return options[name]
 }
 
+func (options Options) lookupKey(name string) string {
+	if _, ok := options[name]; ok {
+		return name
+	}
+	for key := range options {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return ""
+}
+
+func (options Options) lookup(name string) interface{} {
+	var val = options[name]
+	if val != nil {
+		return val
+	}
+	return options[options.lookupKey(name)]
+}
+
 func (options Options) Put(name string, value interface{}) {
 	options[name] = value
 }
@@ -66,22 +86,15 @@ func (options Options) setOptions(osv reflect.Value, parent string) error {
 		if strings.HasPrefix(m.Name, "With") {
 			var name = m.Name[4:]
 			var key = util.LowerCamelCase(name)
-			var val = options.Get(key)
+			var val = options.lookup(key)
 			if val == nil {
 				continue
 			}
 
-			if m.Type.NumIn() != 2 {
-				continue
-			}
-
-			var pt = m.Type.In(1)
-			var pv, err = util.NewReflectValueFromJson(val, pt)
+			var err = callOption(osv, m, val, parent+name)
 			if err != nil {
-				return errors.Errorf("set options '%s' failed, %v", parent+name, err)
+				return err
 			}
-
-			m.Func.Call([]reflect.Value{osv, pv})
 		}
 	}
 
@@ -103,7 +116,7 @@ func (options Options) setOptions(osv reflect.Value, parent string) error {
 
 		if strings.HasSuffix(f.Name, "Server") || strings.HasSuffix(f.Name, "Client") || strings.HasSuffix(f.Name, "Config") {
 			var name = util.LowerCamelCase(f.Name)
-			var val = options.Get(name)
+			var val = options.lookup(name)
 			if val == nil {
 				continue
 			}
@@ -132,6 +145,21 @@ func (options Options) setOptions(osv reflect.Value, parent string) error {
 	return nil
 }
 
+func callOption(osv reflect.Value, m reflect.Method, val interface{}, name string) error {
+	if m.Type.NumIn() != 2 {
+		return nil
+	}
+
+	var pt = m.Type.In(1)
+	var pv, err = util.NewReflectValueFromJson(val, pt)
+	if err != nil {
+		return errors.Errorf("set options '%s' failed, %v", name, err)
+	}
+
+	m.Func.Call([]reflect.Value{osv, pv})
+	return nil
+}
+
 func (options Options) SetOptions(server Optional) error {
 	return options.setOptions(reflect.ValueOf(server), "")
 }
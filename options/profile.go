@@ -0,0 +1,57 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var extensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// profileFile finds the overlay file of the profile for the filename, e.g. config.prod.json for config.json,
+// the one with the same extension is preferred, then the other formats, it returns "" if none exists.
+func profileFile(filename, profile string) string {
+	var ext = filepath.Ext(filename)
+	var base = strings.TrimSuffix(filename, ext)
+	for _, e := range append([]string{ext}, extensions...) {
+		var file = base + "." + profile + e
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file
+		}
+	}
+	return ""
+}
+
+// ReadProfileOptions reads the options from filename, then deep-merges the overlay file of each profile in order
+// if it exists. It returns the chain of the files that were read.
+func ReadProfileOptions(filename string, profiles ...string) (Options, []string, error) {
+	options, err := ReadOptions(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var chain = []string{filename}
+	if len(filename) == 0 {
+		return options, chain, nil
+	}
+
+	for _, profile := range profiles {
+		if len(profile) == 0 {
+			continue
+		}
+
+		var file = profileFile(filename, profile)
+		if file == "" {
+			continue
+		}
+
+		overlay, err := ReadOptions(file)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		options.Merge(overlay)
+		chain = append(chain, file)
+	}
+	return options, chain, nil
+}
@@ -0,0 +1,68 @@
+package options
+
+import (
+	"fmt"
+	"github.com/oylshe1314/framework/errors"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReadOptions reads the options from a json, yaml or toml file by the extension of the filename.
+func ReadOptions(filename string) (Options, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case "", ".json":
+		return ReadJson(filename)
+	case ".yaml", ".yml":
+		return ReadYaml(filename)
+	case ".toml":
+		return ReadToml(filename)
+	default:
+		return nil, errors.Error("unsupported config file: ", filename)
+	}
+}
+
+// normalizeObject converts the values decoded from yaml or toml to the types which are decoded from json,
+// numbers to float64, times to RFC3339 strings and the keys of maps to strings.
+func normalizeObject(obj map[string]interface{}) map[string]interface{} {
+	for k, v := range obj {
+		obj[k] = normalizeValue(v)
+	}
+	return obj
+}
+
+func normalizeValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return normalizeObject(v)
+	case map[interface{}]interface{}:
+		var obj = make(map[string]interface{}, len(v))
+		for mk, mv := range v {
+			obj[fmt.Sprint(mk)] = normalizeValue(mv)
+		}
+		return obj
+	case []interface{}:
+		for i, av := range v {
+			v[i] = normalizeValue(av)
+		}
+		return v
+	case []map[string]interface{}:
+		var ary = make([]interface{}, len(v))
+		for i, av := range v {
+			ary[i] = normalizeObject(av)
+		}
+		return ary
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return val
+	}
+}
@@ -0,0 +1,65 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadOptions(t *testing.T) {
+	var files = map[string]string{
+		"config.json": `{"name": "test", "appId": 1, "logConsole": true, "netServer": {"bind": ":8101", "timeout": 1.5}, "sdConfig": {"servers": ["127.0.0.1:2181"]}}`,
+		"config.yaml": "name: test\nappId: 1\nlogConsole: true\nnetServer:\n  bind: \":8101\"\n  timeout: 1.5\nsdConfig:\n  servers:\n    - 127.0.0.1:2181\n",
+		"config.toml": "name = \"test\"\nappId = 1\nlogConsole = true\n[netServer]\nbind = \":8101\"\ntimeout = 1.5\n[sdConfig]\nservers = [\"127.0.0.1:2181\"]\n",
+	}
+
+	var dir = t.TempDir()
+	var expected Options
+	for _, name := range []string{"config.json", "config.yaml", "config.toml"} {
+		var filename = filepath.Join(dir, name)
+		var err = os.WriteFile(filename, []byte(files[name]), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		options, err := ReadOptions(filename)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+
+		if expected == nil {
+			expected = options
+		} else if !reflect.DeepEqual(expected, options) {
+			t.Fatalf("%s: %v, expected: %v", name, options, expected)
+		}
+	}
+}
+
+func TestReadProfileOptions(t *testing.T) {
+	var dir = t.TempDir()
+	var files = map[string]string{
+		"config.json":      `{"name": "test", "netServer": {"bind": ":8101", "network": "tcp"}}`,
+		"config.prod.yaml": "netServer:\n  bind: \":9101\"\n",
+	}
+	for name, content := range files {
+		var err = os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	options, chain, err := ReadProfileOptions(filepath.Join(dir, "config.json"), "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chain) != 2 || chain[1] != filepath.Join(dir, "config.prod.yaml") {
+		t.Fatal("unexpected chain: ", chain)
+	}
+
+	var expected = Options{"name": "test", "netServer": map[string]interface{}{"bind": ":9101", "network": "tcp"}}
+	if !reflect.DeepEqual(expected, options) {
+		t.Fatalf("%v, expected: %v", options, expected)
+	}
+}
@@ -0,0 +1,155 @@
+package options
+
+import (
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/util"
+	"reflect"
+	"strings"
+)
+
+// Reloadable is implemented by the components which can apply changed options at runtime. While reloading,
+// only the With* setters of the options that Reloadable returns true are called, then Reload is called once.
+type Reloadable interface {
+	Reloadable(option string) bool
+	Reload() error
+}
+
+// Diff returns the options of newOptions which are different from oldOptions, the sub options are compared
+// recursively, the options which were removed from newOptions are ignored.
+func Diff(oldOptions, newOptions Options) Options {
+	var diff = Options{}
+	for name, nv := range newOptions {
+		var ov = oldOptions[name]
+		no, ok1 := nv.(map[string]interface{})
+		oo, ok2 := ov.(map[string]interface{})
+		if ok1 && ok2 {
+			var sub = Diff(oo, no)
+			if len(sub) > 0 {
+				diff[name] = map[string]interface{}(sub)
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(ov, nv) {
+			diff[name] = nv
+		}
+	}
+	return diff
+}
+
+type reloadResult struct {
+	applied Options
+	ignored []string
+}
+
+func reloadOptions(osv reflect.Value, parent string, diff, newOptions Options, result *reloadResult) error {
+	reloadable, _ := osv.Interface().(Reloadable)
+
+	var reloaded = false
+	var ost = osv.Type()
+	var mn = ost.NumMethod()
+	for mi := 0; mi < mn; mi++ {
+		var m = ost.Method(mi)
+		if !m.IsExported() || !strings.HasPrefix(m.Name, "With") {
+			continue
+		}
+
+		var name = m.Name[4:]
+		var key = util.LowerCamelCase(name)
+		if diff.lookup(key) == nil {
+			continue
+		}
+
+		if reloadable == nil || !reloadable.Reloadable(key) {
+			result.ignored = append(result.ignored, parent+key)
+			continue
+		}
+
+		var val = newOptions.lookup(key)
+		var err = callOption(osv, m, val, parent+name)
+		if err != nil {
+			return err
+		}
+
+		result.applied[parent+key] = val
+		reloaded = true
+	}
+
+	if reloaded {
+		var err = reloadable.Reload()
+		if err != nil {
+			return errors.Errorf("reload '%s' failed, %v", strings.TrimSuffix(parent, "."), err)
+		}
+	}
+
+	if ost.Kind() == reflect.Pointer {
+		ost = ost.Elem()
+		osv = osv.Elem()
+	}
+
+	if ost.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fn = ost.NumField()
+	for fi := 0; fi < fn; fi++ {
+		var f = ost.Field(fi)
+		if !f.IsExported() {
+			continue
+		}
+
+		if strings.HasSuffix(f.Name, "Server") || strings.HasSuffix(f.Name, "Client") || strings.HasSuffix(f.Name, "Config") {
+			var name = util.LowerCamelCase(f.Name)
+			subDiff, ok := diff.lookup(name).(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			subOptions, ok := newOptions.lookup(name).(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			fv := osv.Field(fi)
+			if fv.Kind() != reflect.Pointer {
+				fv = fv.Addr()
+			}
+
+			if fv.IsNil() {
+				continue
+			}
+
+			if _, ok = fv.Interface().(Optional); !ok {
+				continue
+			}
+
+			var err = reloadOptions(fv, parent+name+".", subDiff, subOptions, result)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reload applies the options of newOptions which are different from oldOptions to the reloadable
+// components of svr, the applied options are logged by LogOptions, the others are ignored with a warning.
+func Reload(logger log.Logger, svr Optional, oldOptions, newOptions Options) error {
+	var diff = Diff(oldOptions, newOptions)
+	if len(diff) == 0 {
+		logger.Info("No option was changed")
+		return nil
+	}
+
+	var result = &reloadResult{applied: Options{}}
+	var err = reloadOptions(reflect.ValueOf(svr), "", diff, newOptions, result)
+	for _, name := range result.ignored {
+		logger.Warnf("Option '%s' was changed but it is not reloadable, restart to apply it", name)
+	}
+	if len(result.applied) > 0 {
+		LogOptions(logger, result.applied)
+	}
+	return err
+}
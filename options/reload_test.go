@@ -0,0 +1,80 @@
+package options
+
+import (
+	"github.com/oylshe1314/framework/log"
+	"testing"
+)
+
+type testReloadConfig struct {
+	level  string
+	bind   string
+	reload int
+}
+
+func (this *testReloadConfig) WithLevel(level string) {
+	this.level = level
+}
+
+func (this *testReloadConfig) WithBind(bind string) {
+	this.bind = bind
+}
+
+func (this *testReloadConfig) Reloadable(option string) bool {
+	return option == "level"
+}
+
+func (this *testReloadConfig) Reload() error {
+	this.reload++
+	return nil
+}
+
+func (this *testReloadConfig) Init() error {
+	return nil
+}
+
+type testReloadServer struct {
+	name string
+
+	TestConfig testReloadConfig
+}
+
+func (this *testReloadServer) WithName(name string) {
+	this.name = name
+}
+
+func (this *testReloadServer) Init() error {
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	var oldOptions = Options{"name": "a", "testConfig": map[string]interface{}{"level": "INFO", "bind": ":80"}}
+	var newOptions = Options{"name": "b", "testConfig": map[string]interface{}{"level": "DEBUG", "bind": ":81"}}
+
+	var svr = &testReloadServer{}
+	var err = oldOptions.Init(svr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Reload(log.DefaultLogger, svr, oldOptions, newOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if svr.name != "a" || svr.TestConfig.bind != ":80" {
+		t.Fatal("the options which are not reloadable were applied")
+	}
+
+	if svr.TestConfig.level != "DEBUG" || svr.TestConfig.reload != 1 {
+		t.Fatal("the reloadable option was not applied")
+	}
+
+	err = Reload(log.DefaultLogger, svr, newOptions, newOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if svr.TestConfig.reload != 1 {
+		t.Fatal("reloaded without changes")
+	}
+}
@@ -0,0 +1,184 @@
+package options
+
+import (
+	"github.com/oylshe1314/framework/util"
+	"reflect"
+	"strings"
+)
+
+var optionalType = reflect.TypeOf((*Optional)(nil)).Elem()
+
+// Schema generates the JSON Schema of the options of the server type by walking its With* methods
+// and *Server, *Client and *Config fields the same way as SetOptions does.
+func Schema(server Optional) map[string]interface{} {
+	var schema = typeSchema(reflect.TypeOf(server))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	return schema
+}
+
+// Example generates an example config of the server type with the zero value of every option.
+func Example(server Optional) Options {
+	return exampleOptions(reflect.TypeOf(server))
+}
+
+func eachOption(ost reflect.Type, fn func(name string, pt reflect.Type, sub bool)) {
+	var mn = ost.NumMethod()
+	for mi := 0; mi < mn; mi++ {
+		var m = ost.Method(mi)
+		if m.IsExported() && strings.HasPrefix(m.Name, "With") && m.Type.NumIn() == 2 {
+			fn(util.LowerCamelCase(m.Name[4:]), m.Type.In(1), false)
+		}
+	}
+
+	if ost.Kind() == reflect.Pointer {
+		ost = ost.Elem()
+	}
+
+	if ost.Kind() != reflect.Struct {
+		return
+	}
+
+	var fc = ost.NumField()
+	for fi := 0; fi < fc; fi++ {
+		var f = ost.Field(fi)
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+
+		if strings.HasSuffix(f.Name, "Server") || strings.HasSuffix(f.Name, "Client") || strings.HasSuffix(f.Name, "Config") {
+			var ft = f.Type
+			if ft.Kind() != reflect.Pointer {
+				ft = reflect.PointerTo(ft)
+			}
+
+			if !ft.Implements(optionalType) {
+				continue
+			}
+
+			fn(util.LowerCamelCase(f.Name), ft, true)
+		}
+	}
+}
+
+func typeSchema(ost reflect.Type) map[string]interface{} {
+	var properties = map[string]interface{}{}
+	eachOption(ost, func(name string, pt reflect.Type, sub bool) {
+		if sub {
+			properties[name] = typeSchema(pt)
+		} else {
+			properties[name] = valueSchema(pt, map[reflect.Type]bool{})
+		}
+	})
+	return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+}
+
+func valueSchema(vt reflect.Type, visited map[reflect.Type]bool) map[string]interface{} {
+	switch vt.Kind() {
+	case reflect.Pointer:
+		return valueSchema(vt.Elem(), visited)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Array, reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": valueSchema(vt.Elem(), visited)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": valueSchema(vt.Elem(), visited)}
+	case reflect.Struct:
+		if visited[vt] {
+			return map[string]interface{}{"type": "object"}
+		}
+		visited[vt] = true
+		defer delete(visited, vt)
+
+		var properties = map[string]interface{}{}
+		eachField(vt, func(name string, ft reflect.Type) {
+			properties[name] = valueSchema(ft, visited)
+		})
+		return map[string]interface{}{"type": "object", "properties": properties}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func eachField(st reflect.Type, fn func(name string, ft reflect.Type)) {
+	var fc = st.NumField()
+	for fi := 0; fi < fc; fi++ {
+		var f = st.Field(fi)
+		if f.Anonymous {
+			var ft = f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				eachField(ft, fn)
+			}
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		var name = f.Tag.Get("json")
+		if name == "-" {
+			continue
+		}
+
+		name, _, _ = strings.Cut(name, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fn(name, f.Type)
+	}
+}
+
+func exampleOptions(ost reflect.Type) Options {
+	var options = Options{}
+	eachOption(ost, func(name string, pt reflect.Type, sub bool) {
+		if sub {
+			options[name] = map[string]interface{}(exampleOptions(pt))
+		} else {
+			options[name] = exampleValue(pt, map[reflect.Type]bool{})
+		}
+	})
+	return options
+}
+
+func exampleValue(vt reflect.Type, visited map[reflect.Type]bool) interface{} {
+	switch vt.Kind() {
+	case reflect.Pointer:
+		return exampleValue(vt.Elem(), visited)
+	case reflect.Bool:
+		return false
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return 0
+	case reflect.String:
+		return ""
+	case reflect.Array, reflect.Slice:
+		return []interface{}{exampleValue(vt.Elem(), visited)}
+	case reflect.Map:
+		return map[string]interface{}{}
+	case reflect.Struct:
+		if visited[vt] {
+			return map[string]interface{}{}
+		}
+		visited[vt] = true
+		defer delete(visited, vt)
+
+		var obj = map[string]interface{}{}
+		eachField(vt, func(name string, ft reflect.Type) {
+			obj[name] = exampleValue(ft, visited)
+		})
+		return obj
+	default:
+		return nil
+	}
+}
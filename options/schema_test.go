@@ -0,0 +1,73 @@
+package options
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testSchemaTarget struct {
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"`
+	Skip string `json:"-"`
+	Next *testSchemaTarget
+}
+
+type testSchemaServer struct {
+	TestConfig testReloadConfig
+}
+
+func (this *testSchemaServer) WithRate(rate float64)               {}
+func (this *testSchemaServer) WithTags(tags []string)              {}
+func (this *testSchemaServer) WithLimits(limits map[string]int)    {}
+func (this *testSchemaServer) WithTarget(target *testSchemaTarget) {}
+func (this *testSchemaServer) WithEnabled(enabled bool)            {}
+func (this *testSchemaServer) Init() error                         { return nil }
+
+func TestSchema(t *testing.T) {
+	var target = map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+		"host": map[string]interface{}{"type": "string"},
+		"port": map[string]interface{}{"type": "integer"},
+		"Next": map[string]interface{}{"type": "object"},
+	}}
+
+	var expected = map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"rate":    map[string]interface{}{"type": "number"},
+			"tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"limits":  map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "integer"}},
+			"target":  target,
+			"enabled": map[string]interface{}{"type": "boolean"},
+			"testConfig": map[string]interface{}{"type": "object", "additionalProperties": false, "properties": map[string]interface{}{
+				"level": map[string]interface{}{"type": "string"},
+				"bind":  map[string]interface{}{"type": "string"},
+			}},
+		},
+	}
+
+	if schema := Schema(&testSchemaServer{}); !reflect.DeepEqual(schema, expected) {
+		t.Fatalf("unexpected schema: %v", schema)
+	}
+}
+
+func TestExample(t *testing.T) {
+	var expected = Options{
+		"rate":       0,
+		"tags":       []interface{}{""},
+		"limits":     map[string]interface{}{},
+		"target":     map[string]interface{}{"host": "", "port": 0, "Next": map[string]interface{}{}},
+		"enabled":    false,
+		"testConfig": map[string]interface{}{"level": "", "bind": ""},
+	}
+
+	if example := Example(&testSchemaServer{}); !reflect.DeepEqual(example, expected) {
+		t.Fatalf("unexpected example: %v", example)
+	}
+
+	// the example is accepted by the strict mode
+	if err := Example(&testSchemaServer{}).Check(&testSchemaServer{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,77 @@
+package options
+
+import (
+	"github.com/oylshe1314/framework/errors"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Secret is an option value resolved from a secret reference, it is masked while being printed.
+type Secret string
+
+func (this Secret) String() string {
+	return "******"
+}
+
+var secretReference = regexp.MustCompile(`\$\{(env|file):([^}]+)}`)
+
+// Resolve replaces the secret references ${env:NAME} and ${file:path} in the string options with the value of the
+// environment variable or the content of the file, the resolved values are Secret so that LogOptions masks them.
+// They are resolved before setting the options, so the options of the numbers, the bools, the strings and the
+// maps or slices of them are parsed from the resolved values, the options of interface{} get the Secret values.
+func Resolve(options Options) error {
+	for k, v := range options {
+		rv, err := resolveValue(v)
+		if err != nil {
+			return errors.Errorf("resolve option '%s' failed, %v", k, err)
+		}
+		options[k] = rv
+	}
+	return nil
+}
+
+func resolveValue(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return v, Resolve(v)
+	case []interface{}:
+		for i, av := range v {
+			rv, err := resolveValue(av)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rv
+		}
+		return v, nil
+	case string:
+		if !secretReference.MatchString(v) {
+			return v, nil
+		}
+
+		var err error
+		var s = secretReference.ReplaceAllStringFunc(v, func(ref string) string {
+			var sub = secretReference.FindStringSubmatch(ref)
+			switch sub[1] {
+			case "env":
+				value, ok := os.LookupEnv(sub[2])
+				if !ok && err == nil {
+					err = errors.Errorf("environment variable '%s' is not set", sub[2])
+				}
+				return value
+			default:
+				buf, e := os.ReadFile(sub[2])
+				if e != nil && err == nil {
+					err = e
+				}
+				return strings.TrimRight(string(buf), "\r\n")
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		return Secret(s), nil
+	default:
+		return val, nil
+	}
+}
@@ -0,0 +1,109 @@
+package options
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvAndSecret(t *testing.T) {
+	var secretFile = filepath.Join(t.TempDir(), "db")
+	var err = os.WriteFile(secretFile, []byte("file-secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TEST_NETSERVER__BIND", ":8102")
+	t.Setenv("TEST_REDISCLIENT__PASSWORD", "${env:TEST_REDIS_PASSWORD}")
+	t.Setenv("TEST_REDIS_PASSWORD", "env-secret")
+
+	var options = Options{
+		"netServer":   map[string]interface{}{"bind": ":8101"},
+		"mysqlClient": map[string]interface{}{"password": "${file:" + secretFile + "}"},
+	}
+	options.Merge(ReadEnv("TEST", options))
+
+	err = Resolve(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bind := options["netServer"].(map[string]interface{})["bind"]; bind != ":8102" {
+		t.Fatal("unexpected bind: ", bind)
+	}
+
+	var password = options["mysqlClient"].(map[string]interface{})["password"]
+	if password != Secret("file-secret") || fmt.Sprint(password) != "******" {
+		t.Fatal("unexpected mysql password: ", password)
+	}
+
+	password = options.lookup("redisClient").(map[string]interface{})["PASSWORD"]
+	if password != Secret("env-secret") {
+		t.Fatal("unexpected redis password: ", password)
+	}
+}
+
+type testSecretConfig struct {
+	port     int
+	debug    bool
+	password string
+	secret   Secret
+	headers  map[string]string
+}
+
+func (this *testSecretConfig) WithPort(port int) {
+	this.port = port
+}
+
+func (this *testSecretConfig) WithDebug(debug bool) {
+	this.debug = debug
+}
+
+func (this *testSecretConfig) WithPassword(password string) {
+	this.password = password
+}
+
+func (this *testSecretConfig) WithSecret(secret Secret) {
+	this.secret = secret
+}
+
+func (this *testSecretConfig) WithHeaders(headers map[string]string) {
+	this.headers = headers
+}
+
+func (this *testSecretConfig) Init() error {
+	return nil
+}
+
+func TestResolveTypes(t *testing.T) {
+	t.Setenv("TEST_PORT", "8080")
+	t.Setenv("TEST_DEBUG", "true")
+	t.Setenv("TEST_TOKEN", "token")
+
+	var options = Options{
+		"port":     "${env:TEST_PORT}",
+		"debug":    "${env:TEST_DEBUG}",
+		"password": "${env:TEST_TOKEN}",
+		"secret":   "${env:TEST_TOKEN}",
+		"headers":  map[string]interface{}{"Authorization": "Bearer ${env:TEST_TOKEN}"},
+	}
+
+	var err = Resolve(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config = &testSecretConfig{}
+	err = options.SetOptions(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.port != 8080 || !config.debug || config.password != "token" || config.secret != "token" {
+		t.Fatalf("unexpected options: %d, %v, %s, %s", config.port, config.debug, config.password, string(config.secret))
+	}
+	if config.headers["Authorization"] != "Bearer token" {
+		t.Fatal("unexpected headers: ", config.headers)
+	}
+}
@@ -0,0 +1,87 @@
+package options
+
+import (
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/util"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+func consumes(keys []string, name string) bool {
+	for _, key := range keys {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (options Options) checkOptions(osv reflect.Value, parent string, unknown *[]string) {
+	var keys []string
+	var ost = osv.Type()
+	var mn = ost.NumMethod()
+	for mi := 0; mi < mn; mi++ {
+		var m = ost.Method(mi)
+		if m.IsExported() && strings.HasPrefix(m.Name, "With") && m.Type.NumIn() == 2 {
+			keys = append(keys, util.LowerCamelCase(m.Name[4:]))
+		}
+	}
+
+	if ost.Kind() == reflect.Pointer {
+		ost = ost.Elem()
+		osv = osv.Elem()
+	}
+
+	if ost.Kind() == reflect.Struct {
+		var fn = ost.NumField()
+		for fi := 0; fi < fn; fi++ {
+			var f = ost.Field(fi)
+			if !f.IsExported() {
+				continue
+			}
+
+			if strings.HasSuffix(f.Name, "Server") || strings.HasSuffix(f.Name, "Client") || strings.HasSuffix(f.Name, "Config") {
+				fv := osv.Field(fi)
+				if fv.Kind() != reflect.Pointer {
+					fv = fv.Addr()
+				}
+
+				if fv.IsNil() {
+					continue
+				}
+
+				if _, ok := fv.Interface().(Optional); !ok {
+					continue
+				}
+
+				var name = util.LowerCamelCase(f.Name)
+				keys = append(keys, name)
+
+				subOptions, ok := options.lookup(name).(map[string]interface{})
+				if ok {
+					Options(subOptions).checkOptions(fv, parent+name+".", unknown)
+				}
+			}
+		}
+	}
+
+	for name := range options {
+		if !consumes(keys, name) {
+			*unknown = append(*unknown, parent+name)
+		}
+	}
+}
+
+// Check returns an error with all the options which are consumed by neither a With* method nor a
+// *Server, *Client or *Config field of the server, it is used in the strict mode to find misspelled options.
+func (options Options) Check(server Optional) error {
+	var unknown []string
+	options.checkOptions(reflect.ValueOf(server), "", &unknown)
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return errors.Error("unknown options: ", strings.Join(unknown, ", "))
+}
@@ -0,0 +1,14 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	var options = Options{"name": "a", "nmae": "b", "testConfig": map[string]interface{}{"LEVEL": "INFO", "bnd": ":80"}}
+
+	var err = options.Check(&testReloadServer{})
+	if err == nil || err.Error() != "unknown options: nmae, testConfig.bnd" {
+		t.Fatal("unexpected error: ", err)
+	}
+}
@@ -0,0 +1,25 @@
+package options
+
+import (
+	"github.com/BurntSushi/toml"
+	"os"
+)
+
+func ReadToml(filename string) (Options, error) {
+	var options = map[string]interface{}{}
+	if len(filename) == 0 {
+		return options, nil
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	err = toml.Unmarshal(buf, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	return normalizeObject(options), nil
+}
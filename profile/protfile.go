@@ -6,3 +6,6 @@ const (
 	ActiveDev  = "dev"
 	ActiveProd = "prod"
 )
+
+// EnvActive is the environment variable to select the active profile if it is not selected by the flag.
+const EnvActive = "PROFILE_ACTIVE"
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/util"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// clockTicks is the USER_HZ of the kernel, the unit of the cpu times in /proc/self/stat.
+const clockTicks = 100
+
+var startTime = time.Now()
+
+var cpuSample struct {
+	sync.Mutex
+	ticks uint64
+	at    time.Time
+}
+
+// detect collects the statistics of the process and the go runtime, the process statistics are read from
+// /proc/self, they are zero on the systems without procfs.
+func detect() *message.MsgServerDetectAck {
+	var ack = &message.MsgServerDetectAck{}
+
+	ack.ProgramHash = ProgramHash
+	ack.DataHash = DataHash
+	ack.ConfigHash = ConfigHash
+	ack.Pid = os.Getpid()
+	ack.Coroutine = runtime.NumGoroutine()
+	ack.Uptime = int64(time.Since(startTime).Seconds())
+	ack.Connections = int(netConnections.Sum() + wsConnections.Sum())
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	ack.HeapAlloc = ms.HeapAlloc
+	ack.HeapSys = ms.HeapSys
+	ack.HeapObjects = ms.HeapObjects
+	ack.NumGC = ms.NumGC
+	ack.GcPauseTotal = ms.PauseTotalNs
+	if ms.NumGC > 0 {
+		ack.GcPauseLast = ms.PauseNs[(ms.NumGC+255)%256]
+	}
+
+	ack.CPU = cpuPercent()
+	ack.Rss = procStatus("VmRSS") * 1024
+	if total := procMeminfo("MemTotal") * 1024; total > 0 {
+		ack.Memory = float64(ack.Rss) * 100 / float64(total)
+	}
+
+	if entries, err := os.ReadDir("/proc/self/fd"); err == nil {
+		ack.Fds = len(entries)
+	}
+	return ack
+}
+
+// cpuPercent returns the cpu usage of the process since the last call, or since the start at the first call.
+// The sample is shared by the process, the window is since the last call of any caller, e.g. the detections
+// from the http and the net servers shorten the windows of each other.
+func cpuPercent() float64 {
+	var ticks, ok = cpuTicks()
+	if !ok {
+		return 0
+	}
+
+	cpuSample.Lock()
+	defer cpuSample.Unlock()
+
+	var now = time.Now()
+	var lastTicks, lastAt = cpuSample.ticks, cpuSample.at
+	if lastAt.IsZero() {
+		lastTicks, lastAt = 0, startTime
+	}
+	cpuSample.ticks, cpuSample.at = ticks, now
+
+	var elapsed = now.Sub(lastAt).Seconds()
+	if elapsed <= 0 || ticks < lastTicks {
+		return 0
+	}
+	return float64(ticks-lastTicks) / clockTicks * 100 / elapsed
+}
+
+// cpuTicks returns the sum of utime and stime in /proc/self/stat.
+func cpuTicks() (uint64, bool) {
+	data, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, false
+	}
+
+	// The command name in the second field may contain spaces, the fields after it start from the state.
+	var i = bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return 0, false
+	}
+
+	var fields = bytes.Fields(data[i+1:])
+	if len(fields) < 13 {
+		return 0, false
+	}
+
+	var utime, stime uint64
+	if util.StringToInteger2(string(fields[11]), &utime) != nil || util.StringToInteger2(string(fields[12]), &stime) != nil {
+		return 0, false
+	}
+	return utime + stime, true
+}
+
+func procStatus(key string) uint64 {
+	return procValue("/proc/self/status", key)
+}
+
+func procMeminfo(key string) uint64 {
+	return procValue("/proc/meminfo", key)
+}
+
+// procValue reads the value of the line "key: value kB" in the file.
+func procValue(filename, key string) uint64 {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	var prefix = []byte(key + ":")
+	var scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line = scanner.Bytes()
+		if !bytes.HasPrefix(line, prefix) {
+			continue
+		}
+
+		var fields = bytes.Fields(line[len(prefix):])
+		if len(fields) == 0 {
+			return 0
+		}
+
+		var value uint64
+		_ = util.StringToInteger2(string(fields[0]), &value)
+		return value
+	}
+	return 0
+}
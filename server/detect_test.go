@@ -0,0 +1,25 @@
+package server
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	var ack = detect()
+	if ack.Pid == 0 || ack.Coroutine == 0 || ack.HeapAlloc == 0 {
+		t.Fatalf("runtime statistics are missing: %+v", ack)
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	if ack.Rss == 0 || ack.Memory <= 0 || ack.Fds == 0 {
+		t.Fatalf("process statistics are missing: %+v", ack)
+	}
+
+	if _, ok := cpuTicks(); !ok {
+		t.Fatal("read cpu ticks failed")
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+const defaultShutdownTimeout = time.Second * 30
+
+// DrainConfig configures the draining while shutting down, the connected clients are notified with
+// the ModId and MsgId frame if they are not 0, and the connections are closed forcibly after the Timeout(milliseconds).
+type DrainConfig struct {
+	Timeout int    `json:"timeout"`
+	ModId   uint16 `json:"modId"`
+	MsgId   uint16 `json:"msgId"`
+}
+
+func (this *DrainConfig) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if this == nil || this.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(this.Timeout)*time.Millisecond)
+}
+
+func (this *DrainConfig) notifiable() bool {
+	return this != nil && (this.ModId != 0 || this.MsgId != 0)
+}
+
+// Drainer is implemented by the servers which can be shut down gracefully, the server.Start calls
+// Shutdown before Close when the process received SIGTERM. The RegisterClient fields of the server are
+// closed before Shutdown, so that the server is removed from the service discovery before draining.
+type Drainer interface {
+	Shutdown(ctx context.Context) error
+}
+
+// registrar is the sd.RegisterClient, closing it removes the server from the service discovery.
+type registrar interface {
+	SetListener(inner, exter *Listener)
+	Close() error
+}
+
+// deregister closes the registrars in the fields of the server and its embedded structs.
+func deregister(svr Server) {
+	for _, r := range findRegistrars(reflect.ValueOf(svr)) {
+		_ = r.Close()
+	}
+}
+
+func findRegistrars(v reflect.Value) []registrar {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var registrars []registrar
+	for i := 0; i < v.NumField(); i++ {
+		var sf, fv = v.Type().Field(i), v.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		if (fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+
+		if r, ok := fv.Interface().(registrar); ok {
+			registrars = append(registrars, r)
+			continue
+		}
+
+		if fv.CanAddr() {
+			if r, ok := fv.Addr().Interface().(registrar); ok {
+				registrars = append(registrars, r)
+				continue
+			}
+		}
+
+		if sf.Anonymous {
+			registrars = append(registrars, findRegistrars(fv)...)
+		}
+	}
+	return registrars
+}
+
+type drainConn interface {
+	Idle() bool
+	Close() error
+}
+
+// closeIdle closes the connections which have no messages being handled, and returns the count of the others.
+func closeIdle[C drainConn](conns []C) int {
+	var busy = 0
+	for _, conn := range conns {
+		if conn.Idle() {
+			_ = conn.Close()
+		} else {
+			busy++
+		}
+	}
+	return busy
+}
+
+func waitDrained(ctx context.Context, drained func() bool) bool {
+	for !drained() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+	return true
+}
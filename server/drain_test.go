@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/net"
+	"math"
+	stdnet "net"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	LoggerServer
+
+	RegisterClient registrar
+	Other          registrar
+	NetServer      NetServer
+}
+
+func (this *testServer) Logger() log.Logger {
+	return log.DefaultLogger
+}
+
+func (this *testServer) Serve() error {
+	return this.NetServer.Serve()
+}
+
+type testRegistrar struct {
+	closed bool
+}
+
+func (this *testRegistrar) SetListener(inner, exter *Listener) {
+}
+
+func (this *testRegistrar) Close() error {
+	this.closed = true
+	return nil
+}
+
+func TestDeregister(t *testing.T) {
+	var r = &testRegistrar{}
+	var svr = &testServer{RegisterClient: r}
+	deregister(svr)
+	if !r.closed {
+		t.Fatal("the register client should be closed")
+	}
+}
+
+func TestNetServerShutdown(t *testing.T) {
+	var saved = expiration
+	expiration = math.MaxInt64
+	defer func() { expiration = saved }()
+
+	var svr = &testServer{}
+	svr.NetServer.WithNetwork("tcp")
+	svr.NetServer.WithBind("127.0.0.1:0")
+	svr.NetServer.WithAddress("127.0.0.1:0")
+	svr.NetServer.WithDrainConfig(&DrainConfig{Timeout: 3000})
+	svr.NetServer.SetServer(svr)
+
+	var handling = make(chan struct{})
+	var release = make(chan struct{})
+	svr.NetServer.MessageHandler(1, 1, func(msg *net.Message) {
+		close(handling)
+		<-release
+	})
+	if err := svr.NetServer.Init(); err != nil {
+		t.Fatal(err)
+	}
+	go svr.Serve()
+	<-svr.NetServer.Ready()
+
+	c, err := stdnet.Dial("tcp", svr.NetServer.Bind())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var client = net.NewConn(c, log.DefaultLogger, &net.ConnMux{})
+	if err = client.Send(1, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	<-handling
+
+	var shutdown = make(chan error, 1)
+	go func() { shutdown <- svr.NetServer.Shutdown(context.Background()) }()
+
+	select {
+	case <-shutdown:
+		t.Fatal("the shutdown should wait for the handler")
+	case <-time.After(time.Millisecond * 300):
+	}
+
+	var begin = time.Now()
+	close(release)
+	select {
+	case <-shutdown:
+	case <-time.After(time.Second * 2):
+		t.Fatal("the shutdown should finish once the handler returned")
+	}
+	if time.Since(begin) > time.Second {
+		t.Fatal("the idle connection should be closed without waiting for the client")
+	}
+
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = c.Read(make([]byte, 1)); err == nil {
+		t.Fatal("the connection should be closed by the server")
+	}
+}
@@ -1,20 +1,16 @@
 package server
 
 import (
-	"bufio"
-	"bytes"
-	"fmt"
+	"context"
 	"github.com/oylshe1314/framework/errors"
 	. "github.com/oylshe1314/framework/http"
 	"github.com/oylshe1314/framework/log"
-	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/metrics"
+	"github.com/oylshe1314/framework/trace"
 	"github.com/oylshe1314/framework/util"
-	"io"
 	"net/http"
-	"os"
-	"os/exec"
-	"regexp"
-	"runtime"
+	"sync/atomic"
+	"time"
 )
 
 type SslConfig struct {
@@ -37,9 +33,13 @@ type HttpServer struct {
 	Listener
 
 	detectable bool
+	logAdmin   bool
+	adminToken string
+	metrics    bool
 
-	ssl  *SslConfig
-	cors *CorsConfig
+	ssl   *SslConfig
+	cors  atomic.Pointer[CorsConfig]
+	drain *DrainConfig
 
 	hs *http.Server
 	sm http.ServeMux
@@ -52,12 +52,42 @@ func (this *HttpServer) WithDetectable(detectable bool) {
 	this.detectable = detectable
 }
 
+// WithLogAdmin enables the endpoint /server/log/level to query and change the log levels at runtime,
+// it requires the admin token.
+func (this *HttpServer) WithLogAdmin(logAdmin bool) {
+	this.logAdmin = logAdmin
+}
+
+// WithAdminToken sets the token of the admin endpoints, the requests carry it in the header
+// "Authorization: Bearer <token>". It can be a secret reference like ${env:ADMIN_TOKEN}.
+func (this *HttpServer) WithAdminToken(adminToken string) {
+	this.adminToken = adminToken
+}
+
+// WithMetrics enables the endpoint /metrics to expose the metrics in the Prometheus text format.
+func (this *HttpServer) WithMetrics(metrics bool) {
+	this.metrics = metrics
+}
+
 func (this *HttpServer) WithSslConfig(sslConfig *SslConfig) {
 	this.ssl = sslConfig
 }
 
 func (this *HttpServer) WithCorsConfig(corsConfig *CorsConfig) {
-	this.cors = corsConfig
+	// the config is replaced while reloading with the requests being served
+	this.cors.Store(corsConfig)
+}
+
+func (this *HttpServer) WithDrainConfig(drainConfig *DrainConfig) {
+	this.drain = drainConfig
+}
+
+func (this *HttpServer) Reloadable(option string) bool {
+	return option == "corsConfig"
+}
+
+func (this *HttpServer) Reload() error {
+	return nil
 }
 
 func (this *HttpServer) SetServer(svr Server) {
@@ -70,30 +100,31 @@ func (this *HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if this.cors != nil {
-		if this.cors.AllowOrigin != "" {
-			w.Header().Set("Access-Control-Allow-Origin", this.cors.AllowOrigin)
+	var cors = this.cors.Load()
+	if cors != nil {
+		if cors.AllowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", cors.AllowOrigin)
 		}
-		if this.cors.AllowCredentials != "" {
-			w.Header().Set("Access-Control-Allow-Credentials", this.cors.AllowCredentials)
+		if cors.AllowCredentials != "" {
+			w.Header().Set("Access-Control-Allow-Credentials", cors.AllowCredentials)
 		}
-		if this.cors.AllowHeaders != "" {
-			w.Header().Set("Access-Control-Allow-Headers", this.cors.AllowHeaders)
+		if cors.AllowHeaders != "" {
+			w.Header().Set("Access-Control-Allow-Headers", cors.AllowHeaders)
 		}
-		if this.cors.AllowMethods != "" {
-			w.Header().Set("Access-Control-Allow-Methods", this.cors.AllowMethods)
+		if cors.AllowMethods != "" {
+			w.Header().Set("Access-Control-Allow-Methods", cors.AllowMethods)
 		}
-		if this.cors.ExposeHeaders != "" {
-			w.Header().Set("Access-Control-Expose-Headers", this.cors.ExposeHeaders)
+		if cors.ExposeHeaders != "" {
+			w.Header().Set("Access-Control-Expose-Headers", cors.ExposeHeaders)
 		}
-		if this.cors.MaxAge != "" {
-			w.Header().Set("Access-Control-Max-Age", this.cors.MaxAge)
+		if cors.MaxAge != "" {
+			w.Header().Set("Access-Control-Max-Age", cors.MaxAge)
 		}
-		if this.cors.RequestHeaders != "" {
-			w.Header().Set("Access-Control-Request-Headers", this.cors.RequestHeaders)
+		if cors.RequestHeaders != "" {
+			w.Header().Set("Access-Control-Request-Headers", cors.RequestHeaders)
 		}
-		if this.cors.RequestMethod != "" {
-			w.Header().Set("Access-Control-Request-Method", this.cors.RequestMethod)
+		if cors.RequestMethod != "" {
+			w.Header().Set("Access-Control-Request-Method", cors.RequestMethod)
 		}
 		if r.Method == http.MethodOptions {
 			_, _ = w.Write(nil)
@@ -101,23 +132,57 @@ func (this *HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	this.sm.ServeHTTP(w, r)
+	var ctx = trace.Extract(r.Context(), r.Header.Get(trace.HeaderTraceparent))
+	ctx, span := trace.Start(ctx, "HTTP "+r.Method, trace.KindServer)
+	defer span.Finish()
+
+	r = r.WithContext(ctx)
+	var sw = &statusWriter{ResponseWriter: w}
+	var begin = time.Now()
+	this.sm.ServeHTTP(sw, r)
+	observeRequest(r.Pattern, sw.status, begin)
+
+	if len(r.Pattern) > 0 {
+		span.SetName("HTTP " + r.Method + " " + r.Pattern)
+	}
+	var status = util.If(sw.status != 0, sw.status, http.StatusOK)
+	span.SetAttribute("path", r.URL.Path)
+	span.SetAttribute("status", status)
+
+	var logger = this.server.Logger()
+	if logger.IsDebugEnabled() {
+		trace.Logger(ctx, logger).WithField("remote", r.RemoteAddr).Debugf("%s %s %d %v", r.Method, r.URL.Path, status, time.Since(begin))
+	}
 }
 
 func (this *HttpServer) FlatHandler(pattern string, handler http.Handler) {
 	this.sm.Handle(pattern, handler)
 }
 
-func (this *HttpServer) Handler(pattern string, handler MessageHandler) {
-	this.FlatHandler(pattern, handler)
+// Handler registers the handler of the pattern, if the timeout is given, the context of the request
+// is cancelled after the timeout or when the handler returns.
+func (this *HttpServer) Handler(pattern string, handler MessageHandler, timeout ...time.Duration) {
+	this.FlatHandler(pattern, timeoutHandler(handler, timeout))
 }
 
-func (this *HttpServer) PostHandler(pattern string, handler PostHandler) {
-	this.FlatHandler(pattern, handler)
+func (this *HttpServer) PostHandler(pattern string, handler PostHandler, timeout ...time.Duration) {
+	this.FlatHandler(pattern, timeoutHandler(handler, timeout))
 }
 
-func (this *HttpServer) GetHandler(pattern string, handler GetHandler) {
-	this.FlatHandler(pattern, handler)
+func (this *HttpServer) GetHandler(pattern string, handler GetHandler, timeout ...time.Duration) {
+	this.FlatHandler(pattern, timeoutHandler(handler, timeout))
+}
+
+func timeoutHandler(handler http.Handler, timeout []time.Duration) http.Handler {
+	if len(timeout) == 0 || timeout[0] <= 0 {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout[0])
+		defer cancel()
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
 
 func (this *HttpServer) FileHandler(pattern string) {
@@ -145,46 +210,22 @@ func (this *HttpServer) Init() (err error) {
 	if this.detectable {
 		this.GetHandler("/server/detect", this.detect)
 	}
-	return nil
-}
 
-func (this *HttpServer) detect(msg *Message) {
-	var ack = &message.MsgServerDetectAck{}
-
-	ack.ProgramHash = ProgramHash
-	ack.DataHash = DataHash
-	ack.ConfigHash = ConfigHash
-	ack.Pid = os.Getpid()
-	ack.Coroutine = runtime.NumGoroutine()
-
-	switch runtime.GOOS {
-	case "linux", "unix", "darwin", "freebsd":
-		result, err := exec.Command(fmt.Sprintf("ps -aux | grep -w %d", ack.Pid)).Output()
-		if err == nil {
-			var pid = util.IntegerToString(ack.Pid)
-			var re = regexp.MustCompile("\\s+")
-			var r = bufio.NewReader(bytes.NewReader(result))
-			for {
-				line, err := r.ReadString('\n')
-				if err == nil {
-					var ss = re.Split(line, -1)
-					if ss[1] != pid {
-						continue
-					}
-
-					_ = util.StringToFloat2(ss[2], &ack.CPU)
-					_ = util.StringToFloat2(ss[3], &ack.Memory)
-					break
-				}
-
-				if err == io.EOF {
-					break
-				}
-			}
+	if this.logAdmin {
+		if len(this.adminToken) == 0 {
+			return errors.Error("'adminToken' cannot be empty when logAdmin is enabled")
 		}
+		this.Handler("/server/log/level", this.adminRequired(this.logLevel))
 	}
 
-	_ = msg.Reply(ack)
+	if this.metrics {
+		this.FlatHandler("/metrics", metrics.Handler())
+	}
+	return nil
+}
+
+func (this *HttpServer) detect(msg *Message) {
+	_ = msg.Reply(detect())
 }
 
 func (this *HttpServer) Serve() (err error) {
@@ -213,6 +254,23 @@ func (this *HttpServer) Serve() (err error) {
 	return err
 }
 
+// Shutdown stops accepting and waits for the requests in flight to finish until ctx or the drain timeout is done.
+func (this *HttpServer) Shutdown(ctx context.Context) (err error) {
+	this.running = false
+
+	ctx, cancel := this.drain.context(ctx)
+	defer cancel()
+
+	this.server.Logger().Info("HttpServer is draining")
+	err = this.hs.Shutdown(ctx)
+	if err != nil {
+		this.server.Logger().Warn("HttpServer drain timeout, ", err)
+		_ = this.hs.Close()
+	}
+	_ = this.Listener.Close()
+	return err
+}
+
 func (this *HttpServer) Close() (err error) {
 	this.running = false
 	_ = this.hs.Close()
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"crypto/subtle"
+	. "github.com/oylshe1314/framework/http"
+	"github.com/oylshe1314/framework/log"
+	"github.com/oylshe1314/framework/message"
+	"github.com/oylshe1314/framework/trace"
+	"net/http"
+	"strings"
+)
+
+// adminRequired rejects the requests without the admin token.
+func (this *HttpServer) adminRequired(handler MessageHandler) MessageHandler {
+	return func(msg *Message) {
+		token, ok := strings.CutPrefix(msg.R.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(this.adminToken)) != 1 {
+			msg.Error(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		handler(msg)
+	}
+}
+
+// logLevel queries the log levels, or sets the level of the logger or a package if the level is given,
+// the level "RESET" removes the override of the package.
+func (this *HttpServer) logLevel(msg *Message) {
+	var req = &message.MsgServerLogLevelReq{}
+	var err = msg.Read(req)
+	if err != nil {
+		msg.Error(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var logger = this.server.Logger()
+	if len(req.Level) > 0 {
+		if len(req.Package) > 0 && strings.EqualFold(req.Level, "RESET") {
+			logger.RemovePackageLevel(req.Package)
+		} else {
+			var level = log.LevelOf(req.Level)
+			if level > log.LevelTrace {
+				msg.Error(http.StatusBadRequest, "incorrect log level: "+req.Level)
+				return
+			}
+
+			if len(req.Package) > 0 {
+				logger.SetPackageLevel(req.Package, level)
+			} else {
+				logger.SetLogLevel(level)
+			}
+		}
+		trace.Logger(msg.Context(), logger).Warnf("The log level was changed, package: '%s', level: %s", req.Package, req.Level)
+	}
+
+	var ack = &message.MsgServerLogLevelAck{Level: logger.LogLevel().String(), Packages: map[string]string{}}
+	for pkg, level := range logger.PackageLevels() {
+		ack.Packages[pkg] = level.String()
+	}
+	_ = msg.Reply(ack)
+}
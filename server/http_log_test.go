@@ -0,0 +1,37 @@
+package server
+
+import (
+	. "github.com/oylshe1314/framework/http"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminRequired(t *testing.T) {
+	var svr = &HttpServer{adminToken: "secret"}
+	var handler = svr.adminRequired(func(msg *Message) {
+		msg.W.WriteHeader(http.StatusOK)
+	})
+
+	var cases = []struct {
+		authorization string
+		status        int
+	}{
+		{"", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		var r = httptest.NewRequest(http.MethodPost, "/server/log/level", nil)
+		if c.authorization != "" {
+			r.Header.Set("Authorization", c.authorization)
+		}
+		var w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Fatalf("%q: expected %d, got %d", c.authorization, c.status, w.Code)
+		}
+	}
+}
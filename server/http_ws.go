@@ -1,9 +1,11 @@
 package server
 
 import (
+	"context"
 	"github.com/gorilla/websocket"
 	. "github.com/oylshe1314/framework/http/ws"
 	"net/http"
+	"sync"
 )
 
 type WebSocketServer struct {
@@ -11,6 +13,9 @@ type WebSocketServer struct {
 	ConnMux
 
 	wsu websocket.Upgrader
+
+	locker  sync.Mutex
+	connMap map[*Conn]struct{}
 }
 
 func (this *WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -23,7 +28,17 @@ func (this *WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	this.server.Logger().Debug("receive a websocket upgrade request, address: ", r.RemoteAddr)
 
 	conn := NewConn(wc, this.server.Logger(), &this.ConnMux)
+	this.locker.Lock()
+	this.connMap[conn] = struct{}{}
+	this.locker.Unlock()
+	wsConnections.Inc(this.Bind())
 	go func() {
+		defer func() {
+			this.locker.Lock()
+			delete(this.connMap, conn)
+			this.locker.Unlock()
+			wsConnections.Dec(this.Bind())
+		}()
 		_ = conn.Serve()
 	}()
 }
@@ -43,5 +58,60 @@ func (this *WebSocketServer) checkOrigin(r *http.Request) bool {
 func (this *WebSocketServer) Init() (err error) {
 	this.wsu.Error = this.errorHandle
 	this.wsu.CheckOrigin = this.checkOrigin
+	this.connMap = make(map[*Conn]struct{})
 	return this.HttpServer.Init()
 }
+
+// Connections returns the count of the active websocket connections.
+func (this *WebSocketServer) Connections() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return len(this.connMap)
+}
+
+func (this *WebSocketServer) conns() []*Conn {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var conns = make([]*Conn, 0, len(this.connMap))
+	for conn := range this.connMap {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Shutdown drains the http requests, then notifies the websocket clients with the closing frame of the drain config,
+// and closes the connections once their messages were handled, the rest are closed forcibly when ctx or the drain
+// timeout is done.
+func (this *WebSocketServer) Shutdown(ctx context.Context) error {
+	ctx, cancel := this.drain.context(ctx)
+	defer cancel()
+
+	var err = this.HttpServer.Shutdown(ctx)
+
+	var conns = this.conns()
+	this.server.Logger().Infof("WebSocketServer is draining, connections: %d", len(conns))
+
+	if this.drain.notifiable() {
+		for _, conn := range conns {
+			_ = conn.Send(this.drain.ModId, this.drain.MsgId, nil)
+		}
+	}
+
+	if !waitDrained(ctx, func() bool { return closeIdle(this.conns()) == 0 }) {
+		conns = this.conns()
+		this.server.Logger().Warnf("WebSocketServer drain timeout, close the rest connections: %d", len(conns))
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}
+	return err
+}
+
+func (this *WebSocketServer) Close() error {
+	var err = this.HttpServer.Close()
+	for _, conn := range this.conns() {
+		_ = conn.Close()
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	json "github.com/json-iterator/go"
+	"github.com/modern-go/reflect2"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// sortedJson encodes the maps with the sorted keys like the standard library. The map encoder of jsoniter with
+// SortMapKeys encodes the values apart from the stream, which loses the indention of the nested maps.
+var sortedJson = json.Config{EscapeHTML: true}.Froze()
+
+func init() {
+	sortedJson.RegisterExtension(&sortedMapExtension{})
+}
+
+type sortedMapExtension struct {
+	json.DummyExtension
+}
+
+func (this *sortedMapExtension) DecorateEncoder(typ reflect2.Type, encoder json.ValEncoder) json.ValEncoder {
+	if typ.Kind() != reflect.Map || typ.Type1().Key().Kind() != reflect.String {
+		return encoder
+	}
+	return &sortedMapEncoder{ValEncoder: encoder, typ: typ}
+}
+
+type sortedMapEncoder struct {
+	json.ValEncoder
+	typ reflect2.Type
+}
+
+func (this *sortedMapEncoder) Encode(ptr unsafe.Pointer, stream *json.Stream) {
+	var mv = reflect.ValueOf(this.typ.UnsafeIndirect(ptr))
+	if mv.IsNil() {
+		stream.WriteNil()
+		return
+	}
+
+	if mv.Len() == 0 {
+		stream.WriteEmptyObject()
+		return
+	}
+
+	var keys = mv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	stream.WriteObjectStart()
+	for i, key := range keys {
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteObjectField(key.String())
+		stream.WriteVal(mv.MapIndex(key).Interface())
+	}
+	stream.WriteObjectEnd()
+}
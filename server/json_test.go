@@ -0,0 +1,18 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestSortedJson(t *testing.T) {
+	var v = map[string]interface{}{"b": map[string]interface{}{"d": 1, "c": map[string]interface{}{}}, "a": []interface{}{"x"}}
+	buf, err := sortedJson.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = "{\n  \"a\": [\n    \"x\"\n  ],\n  \"b\": {\n    \"c\": {},\n    \"d\": 1\n  }\n}"
+	if string(buf) != expected {
+		t.Fatalf("unexpected json:\n%s", buf)
+	}
+}
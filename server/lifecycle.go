@@ -0,0 +1,82 @@
+package server
+
+import "time"
+
+type Hook func(svr Server) error
+
+// Option configures the lifecycle of the server started by Start.
+type Option func(*lifecycle)
+
+// WithBeforeInit adds a hook called after the options were read and before the server is initialized, the server exits if it fails.
+func WithBeforeInit(hook Hook) Option {
+	return func(lc *lifecycle) {
+		lc.beforeInit = append(lc.beforeInit, hook)
+	}
+}
+
+// WithAfterServe adds a hook called after the server started serving, the server exits if it fails.
+// The hook runs as soon as Serve was called on another goroutine, so the listeners may not be bound yet,
+// wait for their Ready if the hook depends on them.
+func WithAfterServe(hook Hook) Option {
+	return func(lc *lifecycle) {
+		lc.afterServe = append(lc.afterServe, hook)
+	}
+}
+
+// WithBeforeClose adds a hook called after the server received a stop signal and before it is shut down.
+func WithBeforeClose(hook Hook) Option {
+	return func(lc *lifecycle) {
+		lc.beforeClose = append(lc.beforeClose, hook)
+	}
+}
+
+// WithAfterClose adds a hook called after the server was closed.
+func WithAfterClose(hook Hook) Option {
+	return func(lc *lifecycle) {
+		lc.afterClose = append(lc.afterClose, hook)
+	}
+}
+
+// WithReload sets the callback called when the server received SIGHUP.
+func WithReload(reload Hook) Option {
+	return func(lc *lifecycle) {
+		lc.reload = reload
+	}
+}
+
+// WithShutdownTimeout sets the time limit of shutting down, default 30 seconds. The before close hooks and the
+// drain share it, then closing the server and the after close hooks have it again, the process exits forcibly if
+// the hooks or the closing exceed it.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(lc *lifecycle) {
+		lc.shutdownTimeout = timeout
+	}
+}
+
+type lifecycle struct {
+	beforeInit  []Hook
+	afterServe  []Hook
+	beforeClose []Hook
+	afterClose  []Hook
+	reload      Hook
+
+	shutdownTimeout time.Duration
+}
+
+func newLifecycle(opts []Option) *lifecycle {
+	var lc = &lifecycle{shutdownTimeout: defaultShutdownTimeout}
+	for _, opt := range opts {
+		opt(lc)
+	}
+	return lc
+}
+
+func runHooks(svr Server, hooks []Hook) error {
+	for _, hook := range hooks {
+		var err = hook(svr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -12,7 +12,8 @@ type Listener struct {
 	address string
 	extra   map[string]any
 
-	l net.Listener
+	l     net.Listener
+	ready chan struct{}
 }
 
 func (this *Listener) WithNetwork(network string) {
@@ -81,6 +82,7 @@ func (this *Listener) Init() (err error) {
 
 	this.network = addr.Network()
 	this.bind = addr.String()
+	this.ready = make(chan struct{})
 
 	return nil
 }
@@ -92,9 +94,21 @@ func (this *Listener) Listen() (err error) {
 	}
 
 	this.bind = this.l.Addr().String()
+	if this.ready != nil {
+		select {
+		case <-this.ready:
+		default:
+			close(this.ready)
+		}
+	}
 	return
 }
 
+// Ready returns a channel closed once the listener was bound by Serve.
+func (this *Listener) Ready() <-chan struct{} {
+	return this.ready
+}
+
 func (this *Listener) Close() (err error) {
 	if this.l != nil {
 		err = this.l.Close()
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"bufio"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/metrics"
+	"github.com/oylshe1314/framework/util"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	netConnections   = metrics.NewGauge("net_server_connections", "The current connections of the net server.", "bind")
+	netAcceptedTotal = metrics.NewCounter("net_server_accepted_total", "The total connections accepted by the net server.", "bind")
+	wsConnections    = metrics.NewGauge("ws_server_connections", "The current connections of the websocket server.", "bind")
+
+	httpRequestsTotal   = metrics.NewCounter("http_requests_total", "The total http requests by the pattern and the status code.", "pattern", "code")
+	httpRequestDuration = metrics.NewHistogram("http_request_duration_seconds", "The latency of the http requests by the pattern.", nil, "pattern")
+)
+
+// statusWriter records the status code written to the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (this *statusWriter) WriteHeader(status int) {
+	if this.status == 0 {
+		this.status = status
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusWriter) Write(p []byte) (int, error) {
+	if this.status == 0 {
+		this.status = http.StatusOK
+	}
+	return this.ResponseWriter.Write(p)
+}
+
+func (this *statusWriter) Flush() {
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (this *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := this.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.Error("the response writer does not support hijacking")
+	}
+	if this.status == 0 {
+		this.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+func (this *statusWriter) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
+
+func observeRequest(pattern string, status int, begin time.Time) {
+	pattern = util.If(len(pattern) > 0, pattern, "unmatched")
+	status = util.If(status != 0, status, http.StatusOK)
+	httpRequestsTotal.Inc(pattern, util.IntegerToString(status))
+	httpRequestDuration.Observe(time.Since(begin).Seconds(), pattern)
+}
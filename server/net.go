@@ -1,9 +1,12 @@
 package server
 
 import (
+	"context"
 	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/message"
 	. "github.com/oylshe1314/framework/net"
 	"runtime/debug"
+	"sync"
 )
 
 type NetServer struct {
@@ -11,12 +14,26 @@ type NetServer struct {
 
 	ConnMux
 
+	detectable bool
+
+	drain *DrainConfig
+
 	running bool
 	server  Server
 
+	locker  sync.Mutex
 	connMap map[*Conn]struct{}
 }
 
+// WithDetectable handles the detect request of message.ModServer and message.MsgServerDetect.
+func (this *NetServer) WithDetectable(detectable bool) {
+	this.detectable = detectable
+}
+
+func (this *NetServer) WithDrainConfig(drainConfig *DrainConfig) {
+	this.drain = drainConfig
+}
+
 func (this *NetServer) SetServer(svr Server) {
 	this.server = svr
 }
@@ -37,10 +54,17 @@ func (this *NetServer) serve() error {
 		}
 
 		conn := NewConn(cc, this.server.Logger(), &this.ConnMux)
+		this.locker.Lock()
 		this.connMap[conn] = struct{}{}
+		this.locker.Unlock()
+		netAcceptedTotal.Inc(this.Bind())
+		netConnections.Inc(this.Bind())
 		go func() {
 			defer func() {
+				this.locker.Lock()
 				delete(this.connMap, conn)
+				this.locker.Unlock()
+				netConnections.Dec(this.Bind())
 			}()
 			_ = conn.Serve()
 		}()
@@ -53,9 +77,24 @@ func (this *NetServer) Init() (err error) {
 	}
 
 	this.connMap = make(map[*Conn]struct{})
+
+	if this.detectable {
+		this.MessageHandler(message.ModServer, message.MsgServerDetect, this.detect)
+	}
 	return this.Listener.Init()
 }
 
+func (this *NetServer) detect(msg *Message) {
+	_ = msg.Reply(detect())
+}
+
+// Connections returns the count of the active connections.
+func (this *NetServer) Connections() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return len(this.connMap)
+}
+
 func (this *NetServer) Serve() (err error) {
 
 	err = this.Listener.Listen()
@@ -75,10 +114,50 @@ func (this *NetServer) Serve() (err error) {
 	return err
 }
 
+func (this *NetServer) conns() []*Conn {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var conns = make([]*Conn, 0, len(this.connMap))
+	for conn := range this.connMap {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Shutdown stops accepting, notifies the connected clients with the closing frame of the drain config,
+// and closes the connections once their messages were handled, the rest are closed forcibly when ctx or
+// the drain timeout is done.
+func (this *NetServer) Shutdown(ctx context.Context) error {
+	this.running = false
+	var err = this.Listener.Close()
+
+	var conns = this.conns()
+	this.server.Logger().Infof("NetServer is draining, connections: %d", len(conns))
+
+	if this.drain.notifiable() {
+		for _, conn := range conns {
+			_ = conn.Send(this.drain.ModId, this.drain.MsgId, nil)
+		}
+	}
+
+	ctx, cancel := this.drain.context(ctx)
+	defer cancel()
+
+	if !waitDrained(ctx, func() bool { return closeIdle(this.conns()) == 0 }) {
+		conns = this.conns()
+		this.server.Logger().Warnf("NetServer drain timeout, close the rest connections: %d", len(conns))
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}
+	return err
+}
+
 func (this *NetServer) Close() error {
 	this.running = false
 	var err = this.Listener.Close()
-	for conn := range this.connMap {
+	for _, conn := range this.conns() {
 		_ = conn.Close()
 	}
 	return err
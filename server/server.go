@@ -1,10 +1,13 @@
 package server
 
 import (
+	"fmt"
 	"github.com/oylshe1314/framework/errors"
 	"github.com/oylshe1314/framework/log"
 	"github.com/oylshe1314/framework/options"
+	"github.com/oylshe1314/framework/trace"
 	"github.com/oylshe1314/framework/util"
+	"path/filepath"
 	"time"
 )
 
@@ -25,8 +28,30 @@ type LoggerServer struct {
 	logDir     string
 	logLevel   log.Level
 	logConsole bool
+	logFormat  string
 
-	logger log.Logger
+	logPackages map[string]string
+
+	logMaxSize  int
+	logMaxAge   int
+	logMaxCount int
+	logCompress bool
+	logLink     bool
+
+	logAsync       int
+	logAsyncPolicy string
+
+	logSyslog   string
+	logLineSink string
+
+	traceExport string
+
+	// the reloaded levels, they are assigned to logLevel and logPackages after validated by Reload
+	reloadLevel    *log.Level
+	reloadPackages map[string]string
+
+	logger   log.Logger
+	exporter *trace.WriterExporter
 }
 
 func (this *LoggerServer) WithName(name string) {
@@ -42,13 +67,79 @@ func (this *LoggerServer) WithLogDir(logDir string) {
 }
 
 func (this *LoggerServer) WithLogLevel(logLevel string) {
-	this.logLevel = log.LevelOf(logLevel)
+	var level = log.LevelOf(logLevel)
+	if this.logger != nil {
+		this.reloadLevel = &level
+		return
+	}
+	this.logLevel = level
 }
 
 func (this *LoggerServer) WithLogConsole(logConsole bool) {
 	this.logConsole = logConsole
 }
 
+// WithLogPackages overrides the log level of the packages, e.g. {"net": "DEBUG"}.
+func (this *LoggerServer) WithLogPackages(logPackages map[string]string) {
+	if this.logger != nil {
+		this.reloadPackages = logPackages
+		return
+	}
+	this.logPackages = logPackages
+}
+
+func (this *LoggerServer) WithLogFormat(logFormat string) {
+	this.logFormat = logFormat
+}
+
+// WithLogMaxSize rotates the log file when it reaches the size in megabytes.
+func (this *LoggerServer) WithLogMaxSize(logMaxSize int) {
+	this.logMaxSize = logMaxSize
+}
+
+// WithLogMaxAge removes the log files older than the days.
+func (this *LoggerServer) WithLogMaxAge(logMaxAge int) {
+	this.logMaxAge = logMaxAge
+}
+
+func (this *LoggerServer) WithLogMaxCount(logMaxCount int) {
+	this.logMaxCount = logMaxCount
+}
+
+func (this *LoggerServer) WithLogCompress(logCompress bool) {
+	this.logCompress = logCompress
+}
+
+// WithLogLink links <logDir>/<name>_<appId>.log to the current log file.
+func (this *LoggerServer) WithLogLink(logLink bool) {
+	this.logLink = logLink
+}
+
+// WithLogAsync writes the logs asynchronously with a buffer of the count of entries.
+func (this *LoggerServer) WithLogAsync(logAsync int) {
+	this.logAsync = logAsync
+}
+
+// WithLogAsyncPolicy sets the policy when the async buffer is full, block, drop or dropDebug.
+func (this *LoggerServer) WithLogAsyncPolicy(logAsyncPolicy string) {
+	this.logAsyncPolicy = logAsyncPolicy
+}
+
+// WithLogSyslog sends the logs to a syslog server, e.g. udp://127.0.0.1:514.
+func (this *LoggerServer) WithLogSyslog(logSyslog string) {
+	this.logSyslog = logSyslog
+}
+
+// WithLogLineSink sends the logs line by line, e.g. tcp://127.0.0.1:5170.
+func (this *LoggerServer) WithLogLineSink(logLineSink string) {
+	this.logLineSink = logLineSink
+}
+
+// WithTraceExport exports the finished trace spans as JSON lines to stdout, or to a file if it is a path.
+func (this *LoggerServer) WithTraceExport(traceExport string) {
+	this.traceExport = traceExport
+}
+
 func (this *LoggerServer) Name() string {
 	return this.name
 }
@@ -82,13 +173,111 @@ func (this *LoggerServer) Init() (err error) {
 		return errors.Error("incorrect 'logLevel' value")
 	}
 
-	this.logger, err = log.NewDailyLogger(this.Name(), this.AppId(), this.logDir, log.WithLevel(this.logLevel), log.WithConsole(this.logConsole))
-	return err
+	var opts = []log.Option{
+		log.WithLevel(this.logLevel),
+		log.WithConsole(this.logConsole),
+		log.WithFormat(this.logFormat),
+		log.WithMaxSize(int64(this.logMaxSize) * 1024 * 1024),
+		log.WithMaxAge(time.Duration(this.logMaxAge) * 24 * time.Hour),
+		log.WithMaxCount(this.logMaxCount),
+		log.WithCompress(this.logCompress),
+		log.WithAsync(this.logAsync),
+	}
+	if len(this.logSyslog) > 0 {
+		opts = append(opts, log.WithSyslog(this.logSyslog))
+	}
+	if len(this.logLineSink) > 0 {
+		opts = append(opts, log.WithLineSink(this.logLineSink))
+	}
+	if len(this.logAsyncPolicy) > 0 {
+		opts = append(opts, log.WithAsyncPolicy(this.logAsyncPolicy))
+	}
+	if this.logLink {
+		opts = append(opts, log.WithLinkName(filepath.Join(this.logDir, fmt.Sprintf("%s_%d.log", this.name, this.appId))))
+	}
+
+	err = checkLogPackages(this.logPackages)
+	if err != nil {
+		return err
+	}
+
+	this.logger, err = log.NewDailyLogger(this.Name(), this.AppId(), this.logDir, opts...)
+	if err != nil {
+		return err
+	}
+
+	this.setLogPackages()
+
+	switch this.traceExport {
+	case "":
+	case "stdout":
+		this.exporter = trace.NewStdoutExporter()
+	default:
+		this.exporter, err = trace.NewFileExporter(this.traceExport)
+		if err != nil {
+			return err
+		}
+	}
+	if this.exporter != nil {
+		trace.SetExporter(this.exporter)
+	}
+	return nil
+}
+
+func checkLogPackages(logPackages map[string]string) error {
+	for pkg, level := range logPackages {
+		if log.LevelOf(level) > log.LevelTrace {
+			return errors.Errorf("incorrect 'logPackages' value of the package '%s'", pkg)
+		}
+	}
+	return nil
+}
+
+func (this *LoggerServer) setLogPackages() {
+	for pkg := range this.logger.PackageLevels() {
+		this.logger.RemovePackageLevel(pkg)
+	}
+	for pkg, level := range this.logPackages {
+		this.logger.SetPackageLevel(pkg, log.LevelOf(level))
+	}
+}
+
+func (this *LoggerServer) Reloadable(option string) bool {
+	return option == "logLevel" || option == "logPackages"
+}
+
+func (this *LoggerServer) Reload() error {
+	var level, packages = this.logLevel, this.logPackages
+	if this.reloadLevel != nil {
+		level = *this.reloadLevel
+	}
+	if this.reloadPackages != nil {
+		packages = this.reloadPackages
+	}
+	this.reloadLevel, this.reloadPackages = nil, nil
+
+	if level > log.LevelTrace {
+		return errors.Error("incorrect 'logLevel' value")
+	}
+
+	var err = checkLogPackages(packages)
+	if err != nil {
+		return err
+	}
+
+	this.logLevel, this.logPackages = level, packages
+	this.logger.SetLogLevel(this.logLevel)
+	this.setLogPackages()
+	return nil
 }
 
 func (this *LoggerServer) Close() (err error) {
+	if this.exporter != nil {
+		trace.SetExporter(nil)
+		_ = this.exporter.Close()
+		this.exporter = nil
+	}
 	if this.logger != nil {
-		time.Sleep(time.Second)
 		err = this.logger.Close()
 		this.logger = nil
 	}
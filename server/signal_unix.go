@@ -0,0 +1,11 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// levelSignal toggles the log level between DEBUG and the previous one.
+var levelSignal os.Signal = syscall.SIGUSR1
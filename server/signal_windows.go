@@ -0,0 +1,8 @@
+//go:build windows
+
+package server
+
+import "os"
+
+// levelSignal is not supported on windows.
+var levelSignal os.Signal
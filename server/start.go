@@ -1,9 +1,11 @@
 package server
 
 import (
+	"context"
 	"crypto/md5"
 	"flag"
 	"fmt"
+	"github.com/oylshe1314/framework/errors"
 	"github.com/oylshe1314/framework/log"
 	"github.com/oylshe1314/framework/options"
 	"github.com/oylshe1314/framework/profile"
@@ -12,7 +14,9 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
+	"time"
 )
 
 var (
@@ -21,19 +25,29 @@ var (
 	ConfigHash  = "EMPTY"
 )
 
-func Start(svc Server) {
-	os.Exit(start(svc))
+func Start(svr Server, opts ...Option) {
+	os.Exit(start(svr, newLifecycle(opts)))
 }
 
-func start(svr Server) int {
+func start(svr Server, lc *lifecycle) int {
 
 	var showVersion bool
+	var showSchema bool
+	var showExample bool
+	var strict bool
 	var configFile string
+	var activeProfile string
+	var envPrefix string
 	var flagOptions options.FlagOptions
 
 	flag.BoolVar(&showVersion, "v", false, "# Show version information and exit.")
 	flag.BoolVar(&showVersion, "version", false, "# Show version information and exit.")
-	flag.StringVar(&configFile, "conf", "config.json", "# Start the server with a config file.")
+	flag.BoolVar(&showSchema, "schema", false, "# Show the JSON Schema of the config and exit.")
+	flag.BoolVar(&showExample, "example", false, "# Show an example config and exit.")
+	flag.BoolVar(&strict, "strict", false, "# Fail if the config has any unknown option.")
+	flag.StringVar(&configFile, "conf", "config.json", "# Start the server with a config file, json, yaml or toml.")
+	flag.StringVar(&activeProfile, "profile", "", "# Select the active profile, the config file is overlaid by config.<profile>.json.")
+	flag.StringVar(&envPrefix, "envPrefix", "", "# Overlay the config with the environment variables, format: PREFIX_OPTION__SUBOPTION=value.")
 	flag.Var(&flagOptions, "D", "# Used to define configuration options, format: option.subOption=value.")
 	flag.Parse()
 
@@ -42,6 +56,22 @@ func start(svr Server) int {
 		return 0
 	}
 
+	if showSchema || showExample {
+		var v interface{} = options.Example(svr)
+		if showSchema {
+			v = options.Schema(svr)
+		}
+
+		buf, err := sortedJson.MarshalIndent(v, "", "    ")
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		fmt.Println(string(buf))
+		return 0
+	}
+
 	if util.Unix() >= expiration {
 		fmt.Println("The server was expired")
 		return 0
@@ -49,7 +79,12 @@ func start(svr Server) int {
 
 	logVersion(log.DefaultLogger)
 
-	log.DefaultLogger.Info("Config: ", configFile)
+	if len(activeProfile) == 0 {
+		activeProfile = os.Getenv(profile.EnvActive)
+	}
+	if len(activeProfile) > 0 {
+		profile.Active = activeProfile
+	}
 
 	hashAll, _, err := util.HashAll(md5.New(), true, nil, []string{filepath.Dir(os.Args[0])}, nil)
 	if err != nil {
@@ -59,19 +94,16 @@ func start(svr Server) int {
 
 	ProgramHash = hashAll[0]
 
-	opts, err := options.ReadJson(configFile)
+	opts, chain, err := readOptions(configFile, envPrefix, flagOptions)
 	if err != nil {
-		log.DefaultLogger.Error("Read config file failed, ", err)
+		log.DefaultLogger.Error("Read options failed, ", err)
 		return 1
 	}
 
-	additionalOptions, err := flagOptions.Parse()
-	if err != nil {
-		log.DefaultLogger.Error("Parse flag options failed, ", err)
-		return 1
-	}
+	log.DefaultLogger.Info("Profile: ", profile.Active)
+	log.DefaultLogger.Info("Config: ", strings.Join(chain, " -> "))
 
-	hashAll, _, err = util.HashAll(md5.New(), true, flagOptions, nil, []string{configFile})
+	hashAll, _, err = util.HashAll(md5.New(), true, flagOptions, nil, chain)
 	if err != nil {
 		log.DefaultLogger.Error("Calculate program hash failed, ", err)
 		return 1
@@ -79,10 +111,22 @@ func start(svr Server) int {
 
 	ConfigHash = hashAll[0]
 
-	opts.Merge(additionalOptions)
-
 	options.LogOptions(log.DefaultLogger, opts)
 
+	if strict {
+		err = opts.Check(svr)
+		if err != nil {
+			log.DefaultLogger.Error("Check options failed, ", err)
+			return 1
+		}
+	}
+
+	err = runHooks(svr, lc.beforeInit)
+	if err != nil {
+		log.DefaultLogger.Error("Server before init hook failed, ", err)
+		return 1
+	}
+
 	log.DefaultLogger.Info("Server init")
 	err = opts.Init(svr)
 	if err != nil {
@@ -90,17 +134,86 @@ func start(svr Server) int {
 		return 1
 	}
 
-	return run(svr)
+	if lc.reload == nil {
+		lc.reload = func(svr Server) error {
+			return reloadOptions(svr, configFile, envPrefix, flagOptions, &opts)
+		}
+	}
+
+	return run(svr, lc)
 }
 
-func run(svr Server) int {
+// readOptions reads the options from the config file and its overlay of the active profile, the environment
+// variables and the flags, the latter overrides the former, then resolves the secret references.
+func readOptions(configFile, envPrefix string, flagOptions options.FlagOptions) (options.Options, []string, error) {
+	opts, chain, err := options.ReadProfileOptions(configFile, profile.Active)
+	if err != nil {
+		return nil, nil, errors.Error("read config file failed, ", err)
+	}
 
-	var code = 0
-	var stopped = false
-	var sigChan = make(chan os.Signal)
+	opts.Merge(options.ReadEnv(envPrefix, opts))
+
+	additionalOptions, err := flagOptions.Parse()
+	if err != nil {
+		return nil, nil, errors.Error("parse flag options failed, ", err)
+	}
+
+	opts.Merge(additionalOptions)
+
+	err = options.Resolve(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return opts, chain, nil
+}
+
+// toggleLogLevel switches the log level to DEBUG, or back to the saved level if it is DEBUG already.
+func toggleLogLevel(logger log.Logger, saved *log.Level) {
+	var level = logger.LogLevel()
+	if level != log.LevelDebug {
+		*saved = level
+		level = log.LevelDebug
+	} else {
+		level = util.If(*saved != log.LevelDebug, *saved, log.LevelInfo)
+	}
+
+	logger.SetLogLevel(level)
+	logger.Warn("The log level was changed to ", level)
+}
+
+func reloadOptions(svr Server, configFile, envPrefix string, flagOptions options.FlagOptions, current *options.Options) error {
+	opts, chain, err := readOptions(configFile, envPrefix, flagOptions)
+	if err != nil {
+		return err
+	}
+
+	err = options.Reload(svr.Logger(), svr, *current, opts)
+	if err != nil {
+		return err
+	}
+
+	*current = opts
+
+	hashAll, _, err := util.HashAll(md5.New(), true, flagOptions, nil, chain)
+	if err == nil {
+		ConfigHash = hashAll[0]
+		svr.Logger().Info("Config-Hash: ", ConfigHash)
+	}
+	return nil
+}
+
+func run(svr Server, lc *lifecycle) int {
+
+	var sigChan = make(chan os.Signal, 1)
+	var serveChan = make(chan error, 1)
 
 	runtime.GOMAXPROCS(runtime.NumCPU())
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGKILL)
+	var signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+	if levelSignal != nil {
+		signals = append(signals, levelSignal)
+	}
+	signal.Notify(sigChan, signals...)
+	defer signal.Stop(sigChan)
 
 	var logger = svr.Logger()
 
@@ -109,24 +222,87 @@ func run(svr Server) int {
 	logger.Info("Data-Hash: ", DataHash)
 	logger.Info("Config-Hash: ", ConfigHash)
 	logger.Info("Profile-Active: ", profile.Active)
-	go func(pCode *int) {
-		err := svr.Serve()
-		if err == nil {
-			logger.Info("Server stopped")
-		} else {
-			if !stopped {
-				*pCode = 1
+	go func() {
+		serveChan <- svr.Serve()
+	}()
+
+	var code = 0
+	var sig os.Signal
+	var savedLevel = logger.LogLevel()
+	var err = runHooks(svr, lc.afterServe)
+	if err != nil {
+		code = 1
+		logger.Error("Server after serve hook failed, ", err)
+	} else {
+	loop:
+		for {
+			select {
+			case sig = <-sigChan:
+				switch sig {
+				case syscall.SIGHUP:
+					if lc.reload == nil {
+						logger.Warn("Server received SIGHUP, but no reload callback")
+						continue
+					}
+
+					logger.Info("Server reload")
+					err = lc.reload(svr)
+					if err != nil {
+						logger.Error("Server reload failed, ", err)
+					}
+				case levelSignal:
+					toggleLogLevel(logger, &savedLevel)
+				default:
+					logger.Info("Server received signal: ", sig)
+					break loop
+				}
+			case err = <-serveChan:
+				if err == nil {
+					logger.Info("Server stopped")
+				} else {
+					code = 1
+					logger.Error("Server start failed, ", err)
+				}
+				break loop
 			}
-			logger.Error("Server start failed, ", err)
 		}
-		if !stopped {
-			sigChan <- syscall.SIGQUIT
+	}
+
+	// the before close hooks and the drain share the time limit, the drain is limited by its context, and the
+	// process exits forcibly only if the hooks or the closing exceed it
+	var deadline = time.Now().Add(lc.shutdownTimeout)
+	var timer = exitAfter(logger, lc.shutdownTimeout)
+	err = runHooks(svr, lc.beforeClose)
+	timer.Stop()
+	if err != nil {
+		logger.Error("Server before close hook failed, ", err)
+	}
+
+	if drainer, ok := svr.(Drainer); ok && sig == syscall.SIGTERM {
+		logger.Info("Server shutting down")
+		deregister(svr)
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		err = drainer.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			logger.Error("Server shutdown failed, ", err)
 		}
-	}(&code)
+	}
 
-	<-sigChan
-	stopped = true
+	timer = exitAfter(logger, lc.shutdownTimeout)
+	defer timer.Stop()
 	_ = svr.Close()
-	close(sigChan)
+
+	err = runHooks(svr, lc.afterClose)
+	if err != nil {
+		logger.Error("Server after close hook failed, ", err)
+	}
 	return code
 }
+
+func exitAfter(logger log.Logger, timeout time.Duration) *time.Timer {
+	return time.AfterFunc(timeout, func() {
+		logger.Error("Server shutdown timeout, exit forcibly")
+		os.Exit(1)
+	})
+}
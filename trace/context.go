@@ -0,0 +1,182 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/oylshe1314/framework/errors"
+	"github.com/oylshe1314/framework/log"
+	"math/rand/v2"
+)
+
+// HeaderTraceparent is the W3C trace context header, it is also the key in the frame metadata.
+const HeaderTraceparent = "traceparent"
+
+const FlagSampled byte = 0x01
+
+type TraceId [16]byte
+
+func (id TraceId) IsValid() bool {
+	return id != TraceId{}
+}
+
+func (id TraceId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+type SpanId [8]byte
+
+func (id SpanId) IsValid() bool {
+	return id != SpanId{}
+}
+
+func (id SpanId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func newTraceId() (id TraceId) {
+	for !id.IsValid() {
+		putUint64(id[:8], rand.Uint64())
+		putUint64(id[8:], rand.Uint64())
+	}
+	return
+}
+
+func newSpanId() (id SpanId) {
+	for !id.IsValid() {
+		putUint64(id[:], rand.Uint64())
+	}
+	return
+}
+
+func putUint64(b []byte, v uint64) {
+	for i := 0; i < 8; i++ {
+		b[i] = byte(v >> (56 - 8*i))
+	}
+}
+
+// SpanContext identifies a span across the processes.
+type SpanContext struct {
+	TraceId TraceId
+	SpanId  SpanId
+	Flags   byte
+}
+
+func (this SpanContext) IsValid() bool {
+	return this.TraceId.IsValid() && this.SpanId.IsValid()
+}
+
+func (this SpanContext) Sampled() bool {
+	return this.Flags&FlagSampled != 0
+}
+
+// Traceparent formats the span context as the W3C traceparent, version 00.
+func (this SpanContext) Traceparent() string {
+	if !this.IsValid() {
+		return ""
+	}
+
+	var buf = make([]byte, 55)
+	copy(buf, "00-")
+	hex.Encode(buf[3:35], this.TraceId[:])
+	buf[35] = '-'
+	hex.Encode(buf[36:52], this.SpanId[:])
+	buf[52] = '-'
+	hex.Encode(buf[53:55], []byte{this.Flags})
+	return string(buf)
+}
+
+// ParseTraceparent parses the W3C traceparent, the fields after the flags of the future versions are ignored.
+func ParseTraceparent(traceparent string) (sc SpanContext, err error) {
+	if len(traceparent) < 55 || traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return sc, errors.Errorf("invalid traceparent '%s'", traceparent)
+	}
+
+	var version [1]byte
+	if _, err = hex.Decode(version[:], []byte(traceparent[0:2])); err != nil || version[0] == 0xff {
+		return sc, errors.Errorf("invalid traceparent version '%s'", traceparent[0:2])
+	}
+
+	if version[0] == 0 && len(traceparent) != 55 || len(traceparent) > 55 && traceparent[55] != '-' {
+		return sc, errors.Errorf("invalid traceparent '%s'", traceparent)
+	}
+
+	var flags [1]byte
+	if _, err = hex.Decode(sc.TraceId[:], []byte(traceparent[3:35])); err != nil {
+		return sc, errors.Errorf("invalid trace id '%s'", traceparent[3:35])
+	}
+	if _, err = hex.Decode(sc.SpanId[:], []byte(traceparent[36:52])); err != nil {
+		return sc, errors.Errorf("invalid span id '%s'", traceparent[36:52])
+	}
+	if _, err = hex.Decode(flags[:], []byte(traceparent[53:55])); err != nil {
+		return sc, errors.Errorf("invalid trace flags '%s'", traceparent[53:55])
+	}
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return SpanContext{}, errors.Errorf("invalid traceparent '%s'", traceparent)
+	}
+	return sc, nil
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemote returns a copy of ctx with the span context received from a remote process.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the current span in ctx, or the remote one.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// Extract returns a copy of ctx with the span context of the traceparent, or ctx if it is invalid.
+func Extract(ctx context.Context, traceparent string) context.Context {
+	if len(traceparent) == 0 {
+		return ctx
+	}
+
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemote(ctx, sc)
+}
+
+// Inject returns the traceparent of the span context in ctx, or an empty string.
+func Inject(ctx context.Context) string {
+	return SpanContextFromContext(ctx).Traceparent()
+}
+
+// Fields returns the log fields of the trace id and the span id in ctx.
+func Fields(ctx context.Context) log.Fields {
+	var sc = SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return log.Fields{"traceId": sc.TraceId.String(), "spanId": sc.SpanId.String()}
+}
+
+// Logger returns an entry of the logger with the trace fields of ctx, so that the logs written by it can be
+// correlated with the spans.
+func Logger(ctx context.Context, logger log.Logger) *log.Entry {
+	return logger.WithFields(Fields(ctx))
+}
@@ -0,0 +1,81 @@
+package trace
+
+import (
+	json "github.com/json-iterator/go"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type spanRecord struct {
+	TraceId    string                 `json:"traceId"`
+	SpanId     string                 `json:"spanId"`
+	ParentId   string                 `json:"parentId,omitempty"`
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`
+	Start      string                 `json:"start"`
+	Duration   int64                  `json:"duration"` //microseconds
+	Error      string                 `json:"error,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// WriterExporter writes the spans to the writer as JSON lines.
+type WriterExporter struct {
+	locker sync.Mutex
+	w      io.Writer
+}
+
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+func NewStdoutExporter() *WriterExporter {
+	return NewWriterExporter(os.Stdout)
+}
+
+// NewFileExporter appends the spans to the file.
+func NewFileExporter(filename string) (*WriterExporter, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterExporter(file), nil
+}
+
+func (this *WriterExporter) Export(span *Span) {
+	var record = &spanRecord{
+		TraceId:    span.TraceId.String(),
+		SpanId:     span.SpanId.String(),
+		Name:       span.Name,
+		Kind:       span.Kind,
+		Start:      span.Start.Format(time.RFC3339Nano),
+		Duration:   span.Duration().Microseconds(),
+		Error:      span.Error,
+		Attributes: span.Attributes(),
+	}
+	if span.Parent.IsValid() {
+		record.ParentId = span.Parent.String()
+	}
+
+	buf, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	buf = append(buf, '\n')
+
+	this.locker.Lock()
+	_, _ = this.w.Write(buf)
+	this.locker.Unlock()
+}
+
+// Close closes the writer if it is not the stdout or the stderr.
+func (this *WriterExporter) Close() error {
+	if this.w == os.Stdout || this.w == os.Stderr {
+		return nil
+	}
+	if closer, ok := this.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
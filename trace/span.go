@@ -0,0 +1,129 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	KindInternal = "internal"
+	KindServer   = "server"
+	KindClient   = "client"
+)
+
+type Span struct {
+	SpanContext
+	Parent SpanId
+	Name   string
+	Kind   string
+	Start  time.Time
+	End    time.Time
+	Error  string
+
+	locker     sync.Mutex
+	attributes map[string]interface{}
+	ended      bool
+}
+
+// Start starts a span as the child of the span or the remote span context in ctx, or a new trace if there is
+// neither, and returns a copy of ctx with the span.
+func Start(ctx context.Context, name string, kind string) (context.Context, *Span) {
+	var span = &Span{Name: name, Kind: kind, Start: time.Now()}
+
+	var parent = SpanContextFromContext(ctx)
+	if parent.IsValid() {
+		span.TraceId = parent.TraceId
+		span.Flags = parent.Flags
+		span.Parent = parent.SpanId
+	} else {
+		span.TraceId = newTraceId()
+		span.Flags = FlagSampled
+	}
+	span.SpanId = newSpanId()
+	return ContextWithSpan(ctx, span), span
+}
+
+func (this *Span) SetName(name string) {
+	this.locker.Lock()
+	this.Name = name
+	this.locker.Unlock()
+}
+
+func (this *Span) SetAttribute(key string, value interface{}) {
+	this.locker.Lock()
+	if this.attributes == nil {
+		this.attributes = map[string]interface{}{}
+	}
+	this.attributes[key] = value
+	this.locker.Unlock()
+}
+
+func (this *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	this.locker.Lock()
+	this.Error = err.Error()
+	this.locker.Unlock()
+}
+
+// Attributes returns a copy of the attributes.
+func (this *Span) Attributes() map[string]interface{} {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var attributes = make(map[string]interface{}, len(this.attributes))
+	for key, value := range this.attributes {
+		attributes[key] = value
+	}
+	return attributes
+}
+
+func (this *Span) Duration() time.Duration {
+	return this.End.Sub(this.Start)
+}
+
+// Finish ends the span and exports it if it is sampled, the later calls do nothing.
+func (this *Span) Finish() {
+	this.locker.Lock()
+	if this.ended {
+		this.locker.Unlock()
+		return
+	}
+	this.ended = true
+	this.End = time.Now()
+	this.locker.Unlock()
+
+	if !this.Sampled() {
+		return
+	}
+
+	if exporter := getExporter(); exporter != nil {
+		exporter.Export(this)
+	}
+}
+
+type Exporter interface {
+	Export(span *Span)
+}
+
+type exporterHolder struct {
+	exporter Exporter
+}
+
+var exporter atomic.Pointer[exporterHolder]
+
+// SetExporter sets the exporter of the finished spans, nil disables the exporting.
+func SetExporter(e Exporter) {
+	exporter.Store(&exporterHolder{exporter: e})
+}
+
+func getExporter() Exporter {
+	var holder = exporter.Load()
+	if holder == nil {
+		return nil
+	}
+	return holder.exporter
+}
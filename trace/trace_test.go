@@ -0,0 +1,74 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	json "github.com/json-iterator/go"
+	"testing"
+)
+
+func TestTraceparent(t *testing.T) {
+	var traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Sampled() || sc.Traceparent() != traceparent {
+		t.Fatalf("unexpected span context %s", sc.Traceparent())
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	} {
+		if _, err = ParseTraceparent(invalid); err == nil {
+			t.Errorf("'%s' should be invalid", invalid)
+		}
+	}
+
+	if _, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("the future version should be accepted, %v", err)
+	}
+}
+
+func TestSpan(t *testing.T) {
+	var buf bytes.Buffer
+	SetExporter(NewWriterExporter(&buf))
+	defer SetExporter(nil)
+
+	var ctx = Extract(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, parent := Start(ctx, "parent", KindServer)
+	_, child := Start(ctx, "child", KindClient)
+	child.SetAttribute("key", "value")
+	child.Finish()
+	parent.Finish()
+	parent.Finish()
+
+	if parent.TraceId.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || parent.Parent.String() != "00f067aa0ba902b7" {
+		t.Fatal("the span should be the child of the remote span context")
+	}
+	if child.TraceId != parent.TraceId || child.Parent != parent.SpanId {
+		t.Fatal("the span should be the child of the span in the context")
+	}
+
+	var lines = bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 exported spans, got %d", len(lines))
+	}
+
+	var record spanRecord
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Name != "child" || record.ParentId != parent.SpanId.String() || record.Attributes["key"] != "value" {
+		t.Fatalf("unexpected record %s", lines[0])
+	}
+
+	if fields := Fields(ctx); fields["traceId"] != parent.TraceId.String() || fields["spanId"] != parent.SpanId.String() {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
@@ -145,23 +145,22 @@ func fromJsonValue(val interface{}, tv reflect.Value) error {
 				tv.Set(reflect.MakeMap(tv.Type()))
 			}
 
-			if tt.Elem().Kind() != reflect.Interface {
-				for jok, jov := range obj {
-					tv.SetMapIndex(reflect.ValueOf(jok), reflect.ValueOf(jov))
-				}
-			} else {
-				var tet = tt.Elem()
-				for mk, mv := range obj {
-					var tev = reflect.Zero(tet)
-					var err = fromJsonValue(mv, tev)
-					if err != nil {
-						return err
-					}
-					tv.SetMapIndex(reflect.ValueOf(mk), tev)
+			var tet = tt.Elem()
+			for mk, mv := range obj {
+				var tev = reflect.New(tet).Elem()
+				var err = fromJsonValue(mv, tev)
+				if err != nil {
+					return err
 				}
+				tv.SetMapIndex(reflect.ValueOf(mk).Convert(tt.Key()), tev)
 			}
 			return nil
 		default:
+			// the values of the named string types, e.g. the resolved secrets, are parsed as the strings
+			if vt.Kind() == reflect.String {
+				val = reflect.ValueOf(val).String()
+			}
+
 			switch rlv := val.(type) {
 			case bool:
 				if tt.Kind() == reflect.String {